@@ -0,0 +1,281 @@
+// Package calc evaluates the simple arithmetic expressions which can be
+// entered in the weight, volume and quantity fields, e.g. "6*330", "1.5kg",
+// "6x0.33l" or "persons*2".
+package calc
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Quantity is the kind of quantity an expression describes
+type Quantity int
+
+const (
+	// Count is a plain number, units are not allowed
+	Count Quantity = iota
+	// Mass is a mass in gram
+	Mass
+	// Volume is a volume in milliliter
+	Volume
+)
+
+type unit struct {
+	quantity Quantity
+	factor   float64
+}
+
+var units = map[string]unit{
+	"mg": {Mass, 0.001},
+	"g":  {Mass, 1},
+	"kg": {Mass, 1000},
+	"ml": {Volume, 1},
+	"cl": {Volume, 10},
+	"dl": {Volume, 100},
+	"l":  {Volume, 1000},
+}
+
+// Error describes an error in an expression
+type Error struct {
+	// Pos is the position of the error, counted in characters starting at one
+	Pos int
+	Msg string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%s an Position %d", e.Msg, e.Pos)
+}
+
+func errorf(pos int, format string, a ...any) *Error {
+	return &Error{Pos: pos + 1, Msg: fmt.Sprintf(format, a...)}
+}
+
+// Vars are the variables which can be used in an expression
+type Vars map[string]float64
+
+// Eval evaluates the given expression. Numbers followed by a unit are
+// converted to the base unit of the given quantity. The result must be
+// a finite, non-negative number.
+func Eval(expr string, q Quantity) (float64, error) {
+	return EvalVars(expr, q, nil)
+}
+
+// EvalVars evaluates the given expression like Eval, but allows the
+// usage of the given variables.
+func EvalVars(expr string, q Quantity, vars Vars) (float64, error) {
+	p := &parser{text: []rune(expr), quantity: q, vars: vars}
+	p.next()
+	v, err := p.parseExpr()
+	if err != nil {
+		return 0, err
+	}
+	if p.tok.kind != tEOF {
+		return 0, errorf(p.tok.pos, "unerwartetes Zeichen '%s'", p.tok.text)
+	}
+	if math.IsNaN(v.v) || math.IsInf(v.v, 0) {
+		return 0, errorf(0, "Ergebnis ist keine Zahl")
+	}
+	if v.v < 0 {
+		return 0, errorf(0, "Ergebnis ist negativ")
+	}
+	return v.v, nil
+}
+
+// Int evaluates the given expression and rounds the result to the nearest integer
+func Int(expr string, q Quantity) (int, error) {
+	v, err := Eval(expr, q)
+	if err != nil {
+		return 0, err
+	}
+	if v > math.MaxInt32 {
+		return 0, errorf(0, "Ergebnis ist zu groß")
+	}
+	return int(math.Round(v)), nil
+}
+
+type kind int
+
+const (
+	tEOF kind = iota
+	tNumber
+	tIdent
+	tOperator
+)
+
+type token struct {
+	kind kind
+	text string
+	pos  int
+}
+
+// value is an intermediate result. hasUnit is true if the value
+// was derived from a number given with a unit.
+type value struct {
+	v       float64
+	hasUnit bool
+}
+
+type parser struct {
+	text     []rune
+	pos      int
+	tok      token
+	quantity Quantity
+	vars     Vars
+}
+
+func (p *parser) next() {
+	for p.pos < len(p.text) && unicode.IsSpace(p.text[p.pos]) {
+		p.pos++
+	}
+	start := p.pos
+	if p.pos >= len(p.text) {
+		p.tok = token{kind: tEOF, text: "", pos: start}
+		return
+	}
+	c := p.text[p.pos]
+	switch {
+	case unicode.IsDigit(c) || c == '.' || c == ',':
+		for p.pos < len(p.text) && (unicode.IsDigit(p.text[p.pos]) || p.text[p.pos] == '.' || p.text[p.pos] == ',') {
+			p.pos++
+		}
+		p.tok = token{kind: tNumber, text: string(p.text[start:p.pos]), pos: start}
+	case unicode.IsLetter(c):
+		for p.pos < len(p.text) && unicode.IsLetter(p.text[p.pos]) {
+			p.pos++
+		}
+		text := string(p.text[start:p.pos])
+		if text == "x" || text == "X" {
+			p.tok = token{kind: tOperator, text: "*", pos: start}
+		} else {
+			p.tok = token{kind: tIdent, text: text, pos: start}
+		}
+	default:
+		p.pos++
+		text := string(c)
+		if c == '×' {
+			text = "*"
+		}
+		p.tok = token{kind: tOperator, text: text, pos: start}
+	}
+}
+
+func (p *parser) isOp(op string) bool {
+	return p.tok.kind == tOperator && p.tok.text == op
+}
+
+func (p *parser) parseExpr() (value, error) {
+	a, err := p.parseTerm()
+	if err != nil {
+		return value{}, err
+	}
+	for p.isOp("+") || p.isOp("-") {
+		op := p.tok.text
+		p.next()
+		b, err := p.parseTerm()
+		if err != nil {
+			return value{}, err
+		}
+		if op == "+" {
+			a = value{a.v + b.v, a.hasUnit || b.hasUnit}
+		} else {
+			a = value{a.v - b.v, a.hasUnit || b.hasUnit}
+		}
+	}
+	return a, nil
+}
+
+func (p *parser) parseTerm() (value, error) {
+	a, err := p.parseUnary()
+	if err != nil {
+		return value{}, err
+	}
+	for p.isOp("*") || p.isOp("/") {
+		op := p.tok
+		p.next()
+		b, err := p.parseUnary()
+		if err != nil {
+			return value{}, err
+		}
+		if op.text == "*" {
+			if a.hasUnit && b.hasUnit {
+				return value{}, errorf(op.pos, "Einheiten können nicht multipliziert werden")
+			}
+			a = value{a.v * b.v, a.hasUnit || b.hasUnit}
+		} else {
+			if b.hasUnit && !a.hasUnit {
+				return value{}, errorf(op.pos, "Division durch eine Einheit")
+			}
+			if b.v == 0 {
+				return value{}, errorf(op.pos, "Division durch Null")
+			}
+			a = value{a.v / b.v, a.hasUnit && !b.hasUnit}
+		}
+	}
+	return a, nil
+}
+
+func (p *parser) parseUnary() (value, error) {
+	if p.isOp("-") {
+		p.next()
+		v, err := p.parseUnary()
+		if err != nil {
+			return value{}, err
+		}
+		return value{-v.v, v.hasUnit}, nil
+	}
+	return p.parsePrimary()
+}
+
+func (p *parser) parsePrimary() (value, error) {
+	t := p.tok
+	switch t.kind {
+	case tNumber:
+		f, err := strconv.ParseFloat(strings.ReplaceAll(t.text, ",", "."), 64)
+		if err != nil {
+			return value{}, errorf(t.pos, "ungültige Zahl '%s'", t.text)
+		}
+		p.next()
+		if p.tok.kind == tIdent {
+			return p.parseUnit(f)
+		}
+		return value{v: f}, nil
+	case tIdent:
+		if v, ok := p.vars[strings.ToLower(t.text)]; ok {
+			p.next()
+			return value{v: v}, nil
+		}
+		return value{}, errorf(t.pos, "unbekannter Name '%s'", t.text)
+	case tOperator:
+		if t.text == "(" {
+			p.next()
+			v, err := p.parseExpr()
+			if err != nil {
+				return value{}, err
+			}
+			if !p.isOp(")") {
+				return value{}, errorf(p.tok.pos, "')' erwartet")
+			}
+			p.next()
+			return v, nil
+		}
+		return value{}, errorf(t.pos, "unerwartetes Zeichen '%s'", t.text)
+	default:
+		return value{}, errorf(t.pos, "unerwartetes Ende des Ausdrucks")
+	}
+}
+
+func (p *parser) parseUnit(f float64) (value, error) {
+	t := p.tok
+	u, ok := units[strings.ToLower(t.text)]
+	if !ok {
+		return value{}, errorf(t.pos, "unbekannte Einheit '%s'", t.text)
+	}
+	if u.quantity != p.quantity {
+		return value{}, errorf(t.pos, "Einheit '%s' ist hier nicht erlaubt", t.text)
+	}
+	p.next()
+	return value{v: f * u.factor, hasUnit: true}, nil
+}
@@ -0,0 +1,110 @@
+package calc
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestEval(t *testing.T) {
+	tests := []struct {
+		expr     string
+		quantity Quantity
+		want     float64
+		errPos   int
+	}{
+		{"", Count, 0, 1},
+		{"330", Count, 330, 0},
+		{" 6 * 330 ", Count, 1980, 0},
+		{"1+2*3", Count, 7, 0},
+		{"(1+2)*3", Count, 9, 0},
+		{"10/4", Count, 2.5, 0},
+		{"1,5", Count, 1.5, 0},
+		{"-2+3", Count, 1, 0},
+		{"1.5kg", Mass, 1500, 0},
+		{"1,5 kg", Mass, 1500, 0},
+		{"250g", Mass, 250, 0},
+		{"250", Mass, 250, 0},
+		{"500mg", Mass, 0.5, 0},
+		{"6x0.33l", Volume, 1980, 0},
+		{"6 X 0.33 L", Volume, 1980, 0},
+		{"6×330ml", Volume, 1980, 0},
+		{"0.33l*6", Volume, 1980, 0},
+		{"1l+500", Volume, 1500, 0},
+		{"2l/4", Volume, 500, 0},
+		{"1l/250ml", Volume, 4, 0},
+		{"5dl", Volume, 500, 0},
+		{"1kg", Volume, 0, 2},
+		{"1l", Mass, 0, 2},
+		{"1kg", Count, 0, 2},
+		{"2 pfund", Mass, 0, 3},
+		{"1kg*1kg", Mass, 0, 4},
+		{"2/1kg", Mass, 0, 2},
+		{"1/0", Count, 0, 2},
+		{"1/(1-1)", Count, 0, 2},
+		{"1-2", Count, 0, 1},
+		{"-1", Count, 0, 1},
+		{"pi", Count, 0, 1},
+		{"sin(1)", Count, 0, 1},
+		{"(1+2", Count, 0, 5},
+		{"1+", Count, 0, 3},
+		{"1 2", Count, 0, 3},
+		{"1.2.3", Count, 0, 1},
+		{"2^3", Count, 0, 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			got, err := Eval(tt.expr, tt.quantity)
+			if tt.errPos > 0 {
+				if assert.Error(t, err) {
+					assert.EqualValues(t, tt.errPos, err.(*Error).Pos, err.Error())
+				}
+			} else {
+				assert.NoError(t, err)
+				assert.InDelta(t, tt.want, got, 1e-9)
+			}
+		})
+	}
+}
+
+func TestEvalVars(t *testing.T) {
+	vars := Vars{"suggest": 3, "last": 2, "persons": 4}
+	tests := []struct {
+		expr   string
+		want   float64
+		errPos int
+	}{
+		{"suggest+1", 4, 0},
+		{"persons*2", 8, 0},
+		{"Persons x 2", 8, 0},
+		{"(last+suggest)/2", 2.5, 0},
+		{"last-suggest", 0, 1},
+		{"persons*unknown", 0, 9},
+		{"2persons", 0, 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			got, err := EvalVars(tt.expr, Count, vars)
+			if tt.errPos > 0 {
+				if assert.Error(t, err) {
+					assert.EqualValues(t, tt.errPos, err.(*Error).Pos, err.Error())
+				}
+			} else {
+				assert.NoError(t, err)
+				assert.InDelta(t, tt.want, got, 1e-9)
+			}
+		})
+	}
+}
+
+func TestInt(t *testing.T) {
+	v, err := Int("6*0.33l", Volume)
+	assert.NoError(t, err)
+	assert.EqualValues(t, 1980, v)
+
+	v, err = Int("2.6", Count)
+	assert.NoError(t, err)
+	assert.EqualValues(t, 3, v)
+
+	_, err = Int("99999999999", Count)
+	assert.Error(t, err)
+}
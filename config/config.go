@@ -0,0 +1,298 @@
+// Package config reads the server settings. The settings are taken from
+// the defaults, a JSON config file and environment variables, in this order.
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/hneemann/shopping/listen"
+	"io"
+	"log/slog"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Duration is a time.Duration which is written as a string like "30m" or "8d" in the config file
+type Duration time.Duration
+
+// ParseDuration parses a duration. In addition to the units known
+// by time.ParseDuration, the unit "d" is allowed for days.
+func ParseDuration(s string) (Duration, error) {
+	s = strings.TrimSpace(s)
+	if days, ok := strings.CutSuffix(s, "d"); ok {
+		n, err := strconv.ParseFloat(days, 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid duration '%s'", s)
+		}
+		return Duration(n * 24 * float64(time.Hour)), nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid duration '%s'", s)
+	}
+	return Duration(d), nil
+}
+
+func (d *Duration) UnmarshalJSON(b []byte) error {
+	var s string
+	err := json.Unmarshal(b, &s)
+	if err != nil {
+		return fmt.Errorf("duration needs to be a string like \"30m\" or \"8d\"")
+	}
+	*d, err = ParseDuration(s)
+	return err
+}
+
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+// Features contains the features which can be switched off
+type Features struct {
+	// Registration allows new users to create an account
+	Registration bool
+	// AutoCheckout enables the automatic checkout of the shopping cart
+	AutoCheckout bool
+	// Metrics enables the /metrics endpoint. The endpoint requires no
+	// login, so it is disabled by default.
+	Metrics bool
+}
+
+// Config contains all server settings
+type Config struct {
+	// Folder is the data folder
+	Folder string
+	Port   int
+	// Mode is the listener mode: tls, selfsigned or http
+	Mode string
+	Cert string
+	Key  string
+	// TrustedProxies are the proxies whose X-Forwarded headers are trusted in http mode
+	TrustedProxies string
+	Debug          bool
+	// LogLevel is the minimum level of the log messages: debug, info, warn or error
+	LogLevel string
+	// LogFormat is the format of the log messages: text or json
+	LogFormat string
+	// SessionLifetime is the time a login is valid
+	SessionLifetime Duration
+	// DataLifetime is the time the data of an inactive user is kept in memory
+	DataLifetime Duration
+	// HistoryRetention is the time the shopping history is kept
+	HistoryRetention Duration
+	// FlushDelay is the time after the last change at which modified data is written to disk
+	FlushDelay Duration
+	// ShutdownDelay is the time between failing the readiness probe and closing
+	// the listener at shutdown, so that a load balancer can stop sending requests
+	ShutdownDelay Duration
+	// ShutdownTimeout is the time running requests are given to complete at shutdown
+	ShutdownTimeout Duration
+	Features        Features
+}
+
+// Default returns the default settings
+func Default() Config {
+	return Config{
+		Folder:           "data",
+		Port:             8090,
+		Mode:             "tls",
+		Cert:             "cert.pem",
+		Key:              "cert.key",
+		TrustedProxies:   "127.0.0.1,::1",
+		LogLevel:         "info",
+		LogFormat:        "text",
+		SessionLifetime:  Duration(8 * 24 * time.Hour),
+		DataLifetime:     Duration(30 * time.Minute),
+		HistoryRetention: Duration(180 * 24 * time.Hour),
+		FlushDelay:       Duration(10 * time.Second),
+		ShutdownDelay:    Duration(5 * time.Second),
+		ShutdownTimeout:  Duration(10 * time.Second),
+		Features: Features{
+			Registration: true,
+			AutoCheckout: true,
+		},
+	}
+}
+
+// Read reads a config file. Settings missing in the file keep their default value.
+func Read(r io.Reader) (Config, error) {
+	c := Default()
+	dec := json.NewDecoder(r)
+	dec.DisallowUnknownFields()
+	err := dec.Decode(&c)
+	if err != nil {
+		return Config{}, err
+	}
+	return c, nil
+}
+
+// ReadFile reads the given config file. If the name is empty, the defaults are returned.
+func ReadFile(name string) (Config, error) {
+	if name == "" {
+		return Default(), nil
+	}
+	f, err := os.Open(name)
+	if err != nil {
+		return Config{}, err
+	}
+	defer f.Close()
+	c, err := Read(f)
+	if err != nil {
+		return Config{}, fmt.Errorf("config file %s: %w", name, err)
+	}
+	return c, nil
+}
+
+type envVar struct {
+	name string
+	set  func(c *Config, v string) error
+}
+
+func setString(f func(c *Config) *string) func(c *Config, v string) error {
+	return func(c *Config, v string) error {
+		*f(c) = v
+		return nil
+	}
+}
+
+func setInt(f func(c *Config) *int) func(c *Config, v string) error {
+	return func(c *Config, v string) error {
+		i, err := strconv.Atoi(v)
+		if err != nil {
+			return fmt.Errorf("'%s' is not an integer", v)
+		}
+		*f(c) = i
+		return nil
+	}
+}
+
+func setBool(f func(c *Config) *bool) func(c *Config, v string) error {
+	return func(c *Config, v string) error {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return fmt.Errorf("'%s' is not a boolean", v)
+		}
+		*f(c) = b
+		return nil
+	}
+}
+
+func setDuration(f func(c *Config) *Duration) func(c *Config, v string) error {
+	return func(c *Config, v string) error {
+		d, err := ParseDuration(v)
+		if err != nil {
+			return err
+		}
+		*f(c) = d
+		return nil
+	}
+}
+
+var envVars = []envVar{
+	{"SHOPPING_FOLDER", setString(func(c *Config) *string { return &c.Folder })},
+	{"SHOPPING_PORT", setInt(func(c *Config) *int { return &c.Port })},
+	{"SHOPPING_MODE", setString(func(c *Config) *string { return &c.Mode })},
+	{"SHOPPING_CERT", setString(func(c *Config) *string { return &c.Cert })},
+	{"SHOPPING_KEY", setString(func(c *Config) *string { return &c.Key })},
+	{"SHOPPING_TRUSTED_PROXIES", setString(func(c *Config) *string { return &c.TrustedProxies })},
+	{"SHOPPING_DEBUG", setBool(func(c *Config) *bool { return &c.Debug })},
+	{"SHOPPING_LOG_LEVEL", setString(func(c *Config) *string { return &c.LogLevel })},
+	{"SHOPPING_LOG_FORMAT", setString(func(c *Config) *string { return &c.LogFormat })},
+	{"SHOPPING_SESSION_LIFETIME", setDuration(func(c *Config) *Duration { return &c.SessionLifetime })},
+	{"SHOPPING_DATA_LIFETIME", setDuration(func(c *Config) *Duration { return &c.DataLifetime })},
+	{"SHOPPING_HISTORY_RETENTION", setDuration(func(c *Config) *Duration { return &c.HistoryRetention })},
+	{"SHOPPING_FLUSH_DELAY", setDuration(func(c *Config) *Duration { return &c.FlushDelay })},
+	{"SHOPPING_SHUTDOWN_DELAY", setDuration(func(c *Config) *Duration { return &c.ShutdownDelay })},
+	{"SHOPPING_SHUTDOWN_TIMEOUT", setDuration(func(c *Config) *Duration { return &c.ShutdownTimeout })},
+	{"SHOPPING_REGISTRATION", setBool(func(c *Config) *bool { return &c.Features.Registration })},
+	{"SHOPPING_AUTO_CHECKOUT", setBool(func(c *Config) *bool { return &c.Features.AutoCheckout })},
+	{"SHOPPING_METRICS", setBool(func(c *Config) *bool { return &c.Features.Metrics })},
+}
+
+// ApplyEnv overrides the settings by the SHOPPING_* environment variables.
+// The lookup function is usually os.LookupEnv.
+func (c *Config) ApplyEnv(lookup func(string) (string, bool)) error {
+	var errs []error
+	for _, e := range envVars {
+		if v, ok := lookup(e.name); ok {
+			err := e.set(c, strings.TrimSpace(v))
+			if err != nil {
+				errs = append(errs, fmt.Errorf("environment variable %s: %w", e.name, err))
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Validate checks the settings. All problems found are reported.
+func (c *Config) Validate() error {
+	var errs []error
+	fail := func(format string, a ...any) {
+		errs = append(errs, fmt.Errorf(format, a...))
+	}
+	if strings.TrimSpace(c.Folder) == "" {
+		fail("Folder: no data folder given")
+	}
+	if c.Port < 1 || c.Port > 65535 {
+		fail("Port: %d is not between 1 and 65535", c.Port)
+	}
+	switch c.Mode {
+	case "tls", "selfsigned":
+		if c.Cert == "" || c.Key == "" {
+			fail("Cert, Key: certificate and key are required in mode %s", c.Mode)
+		}
+	case "http":
+		if _, err := listen.ParseNetworks(c.TrustedProxies); err != nil {
+			fail("TrustedProxies: %v", err)
+		}
+	default:
+		fail("Mode: '%s' is unknown, use tls, selfsigned or http", c.Mode)
+	}
+	if _, err := c.logLevel(); err != nil {
+		fail("LogLevel: '%s' is unknown, use debug, info, warn or error", c.LogLevel)
+	}
+	if c.LogFormat != "text" && c.LogFormat != "json" {
+		fail("LogFormat: '%s' is unknown, use text or json", c.LogFormat)
+	}
+	if c.SessionLifetime <= 0 {
+		fail("SessionLifetime: needs to be positive")
+	}
+	if c.DataLifetime <= 0 {
+		fail("DataLifetime: needs to be positive")
+	}
+	if c.HistoryRetention < Duration(24*time.Hour) {
+		fail("HistoryRetention: needs to be at least one day")
+	}
+	if c.FlushDelay < Duration(time.Second) {
+		fail("FlushDelay: needs to be at least one second")
+	}
+	if c.ShutdownDelay < 0 {
+		fail("ShutdownDelay: must not be negative")
+	}
+	if c.ShutdownTimeout <= 0 {
+		fail("ShutdownTimeout: needs to be positive")
+	}
+	return errors.Join(errs...)
+}
+
+func (c *Config) logLevel() (slog.Level, error) {
+	var l slog.Level
+	err := l.UnmarshalText([]byte(c.LogLevel))
+	return l, err
+}
+
+// Logger creates the logger writing to w as configured by LogLevel and LogFormat
+func (c *Config) Logger(w io.Writer) *slog.Logger {
+	level, err := c.logLevel()
+	if err != nil {
+		level = slog.LevelInfo
+	}
+	opts := &slog.HandlerOptions{Level: level}
+	if c.LogFormat == "json" {
+		return slog.New(slog.NewJSONHandler(w, opts))
+	}
+	return slog.New(slog.NewTextHandler(w, opts))
+}
@@ -0,0 +1,141 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseDuration(t *testing.T) {
+	tests := []struct {
+		s    string
+		want time.Duration
+		err  bool
+	}{
+		{"30m", 30 * time.Minute, false},
+		{"8d", 8 * 24 * time.Hour, false},
+		{"1.5d", 36 * time.Hour, false},
+		{" 2h ", 2 * time.Hour, false},
+		{"d", 0, true},
+		{"8", 0, true},
+		{"eine Woche", 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.s, func(t *testing.T) {
+			d, err := ParseDuration(tt.s)
+			if tt.err {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+				assert.EqualValues(t, tt.want, d)
+			}
+		})
+	}
+}
+
+func TestRead(t *testing.T) {
+	c, err := Read(strings.NewReader(`{"Port":8443,"Mode":"http","SessionLifetime":"14d","Features":{"Registration":false}}`))
+	assert.NoError(t, err)
+	assert.EqualValues(t, 8443, c.Port)
+	assert.EqualValues(t, "http", c.Mode)
+	assert.EqualValues(t, 14*24*time.Hour, c.SessionLifetime)
+	assert.EqualValues(t, 30*time.Minute, c.DataLifetime)
+	assert.EqualValues(t, "data", c.Folder)
+	assert.False(t, c.Features.Registration)
+	assert.True(t, c.Features.AutoCheckout)
+	assert.False(t, c.Features.Metrics)
+	assert.NoError(t, c.Validate())
+
+	_, err = Read(strings.NewReader(`{"Prot":8443}`))
+	assert.Error(t, err)
+	_, err = Read(strings.NewReader(`{"DataLifetime":30}`))
+	assert.Error(t, err)
+	_, err = Read(strings.NewReader(`{"DataLifetime":"30x"}`))
+	assert.Error(t, err)
+}
+
+func TestApplyEnv(t *testing.T) {
+	env := map[string]string{
+		"SHOPPING_PORT":              "9000",
+		"SHOPPING_FOLDER":            "/var/lib/shopping",
+		"SHOPPING_DEBUG":             "true",
+		"SHOPPING_HISTORY_RETENTION": "365d",
+		"SHOPPING_AUTO_CHECKOUT":     "0",
+		"SHOPPING_LOG_FORMAT":        "json",
+		"SHOPPING_METRICS":           "true",
+	}
+	lookup := func(n string) (string, bool) {
+		v, ok := env[n]
+		return v, ok
+	}
+	c := Default()
+	assert.NoError(t, c.ApplyEnv(lookup))
+	assert.EqualValues(t, 9000, c.Port)
+	assert.EqualValues(t, "/var/lib/shopping", c.Folder)
+	assert.True(t, c.Debug)
+	assert.EqualValues(t, 365*24*time.Hour, c.HistoryRetention)
+	assert.False(t, c.Features.AutoCheckout)
+	assert.EqualValues(t, "json", c.LogFormat)
+	assert.True(t, c.Features.Metrics)
+
+	env = map[string]string{
+		"SHOPPING_PORT":  "neunzig",
+		"SHOPPING_DEBUG": "vielleicht",
+	}
+	c = Default()
+	err := c.ApplyEnv(lookup)
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "SHOPPING_PORT")
+		assert.Contains(t, err.Error(), "SHOPPING_DEBUG")
+	}
+}
+
+func TestValidate(t *testing.T) {
+	def := Default()
+	assert.NoError(t, def.Validate())
+
+	c := Default()
+	c.Port = 0
+	c.Mode = "ftp"
+	c.Folder = " "
+	c.DataLifetime = 0
+	c.HistoryRetention = Duration(time.Hour)
+	c.LogLevel = "verbose"
+	c.LogFormat = "xml"
+	c.ShutdownTimeout = 0
+	c.ShutdownDelay = Duration(-time.Second)
+	c.FlushDelay = Duration(time.Millisecond)
+	err := c.Validate()
+	if assert.Error(t, err) {
+		for _, s := range []string{"Port", "Mode", "Folder", "DataLifetime", "HistoryRetention", "LogLevel", "LogFormat", "ShutdownTimeout", "ShutdownDelay", "FlushDelay"} {
+			assert.Contains(t, err.Error(), s)
+		}
+	}
+
+	c = Default()
+	c.Mode = "http"
+	c.TrustedProxies = "proxy.local"
+	assert.Error(t, c.Validate())
+
+	c = Default()
+	c.Cert = ""
+	assert.Error(t, c.Validate())
+}
+
+func TestLogger(t *testing.T) {
+	c := Default()
+	c.LogLevel = "warn"
+	c.LogFormat = "json"
+	var b bytes.Buffer
+	l := c.Logger(&b)
+	l.Info("hidden")
+	l.Warn("shown", "user", "anna")
+	assert.NotContains(t, b.String(), "hidden")
+	var m map[string]any
+	assert.NoError(t, json.Unmarshal(b.Bytes(), &m))
+	assert.EqualValues(t, "shown", m["msg"])
+	assert.EqualValues(t, "anna", m["user"])
+}
@@ -3,7 +3,6 @@ module github.com/hneemann/shopping
 go 1.25.0
 
 require (
-	github.com/hneemann/parser2 v0.0.0-20251125205354-0e210c0799f8
 	github.com/hneemann/session v0.0.0-20250917051702-4d7e0d523c75
 	github.com/stretchr/testify v1.11.1
 )
@@ -0,0 +1,237 @@
+package item
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"strings"
+	"sync"
+)
+
+const defaultListName = "Zuhause"
+
+// NamedList is a shopping list of an account
+type NamedList struct {
+	Name string
+	// Shared is true if the list shares its catalog of items with
+	// all other shared lists of the account. The quantities are
+	// kept separately.
+	Shared bool
+	List   *ListData
+}
+
+// Account holds all lists of a user. The list shown is selected by each
+// device separately, so the account does not store a selection.
+// The handlers, the scheduler and the flusher access the lists concurrently,
+// so the account and its lists may only be used while holding its lock.
+type Account struct {
+	Lists []*NamedList
+
+	mutex sync.Mutex
+	user  string
+	dirty
+}
+
+// Lock locks the account and all its lists
+func (a *Account) Lock() {
+	a.mutex.Lock()
+}
+
+// Unlock unlocks the account
+func (a *Account) Unlock() {
+	a.mutex.Unlock()
+}
+
+// SetUser sets the name of the user the account belongs to
+func (a *Account) SetUser(user string) {
+	a.user = user
+}
+
+// User returns the name of the user the account belongs to
+func (a *Account) User() string {
+	return a.user
+}
+
+// Named returns the list with the given name. If there is no such list, the
+// first list is returned. If there is no list at all, a default list is created.
+func (a *Account) Named(name string) *NamedList {
+	if len(a.Lists) == 0 {
+		a.Lists = []*NamedList{{Name: defaultListName, List: &ListData{}}}
+	}
+	for _, nl := range a.Lists {
+		if nl.Name == name {
+			return nl
+		}
+	}
+	return a.Lists[0]
+}
+
+// ListByName returns the list with the given name or nil if there is no such list
+func (a *Account) ListByName(name string) *NamedList {
+	for _, nl := range a.Lists {
+		if nl.Name == name {
+			return nl
+		}
+	}
+	return nil
+}
+
+func (a *Account) hasSharedList() bool {
+	for _, nl := range a.Lists {
+		if nl.Shared {
+			return true
+		}
+	}
+	return false
+}
+
+// AddList creates a new list. If shared is true, the new list shares the
+// catalog with the list from. The ids of the items are only unique within
+// a shared catalog, so if the list from is not shared but there already are
+// shared lists, an error is returned.
+func (a *Account) AddList(name string, shared bool, from *NamedList) error {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return errors.New("kein Name angegeben")
+	}
+	for _, nl := range a.Lists {
+		if nl.Name == name {
+			return errors.New("eine Liste mit diesem Namen gibt es schon")
+		}
+	}
+	if shared && !from.Shared && a.hasSharedList() {
+		return errors.New("es gibt schon einen gemeinsamen Katalog, bitte eine Liste mit gemeinsamem Katalog auswählen, um ihn zu teilen")
+	}
+	nl := &NamedList{Name: name, Shared: shared, List: &ListData{}}
+	if shared {
+		syncCatalog(from.List, nl.List)
+		from.Shared = true
+	}
+	slog.Info("created list", "user", a.user, "list", name)
+	a.Lists = append(a.Lists, nl)
+	a.changed()
+	return nil
+}
+
+// DeleteList deletes the list with the given name. The last list can not be deleted.
+func (a *Account) DeleteList(name string) {
+	if len(a.Lists) <= 1 {
+		return
+	}
+	for i, nl := range a.Lists {
+		if nl.Name == name {
+			a.changed()
+			slog.Info("deleted list", "user", a.user, "list", name)
+			a.Lists = append(a.Lists[:i], a.Lists[i+1:]...)
+			return
+		}
+	}
+}
+
+// SyncCatalog copies the catalog of the list src to all other lists
+// sharing the catalog. It is called after the list src was modified.
+func (a *Account) SyncCatalog(src *NamedList) {
+	if !src.Shared {
+		return
+	}
+	for _, nl := range a.Lists {
+		if nl != src && nl.Shared {
+			syncCatalog(src.List, nl.List)
+		}
+	}
+}
+
+// syncCatalog makes the items of dst match the items of src.
+// Only the catalog data is copied, quantities, history and stock are kept.
+func syncCatalog(src, dst *ListData) {
+	dstItems := make(map[int]*Item)
+	for _, i := range dst.Items {
+		dstItems[i.Id] = i
+	}
+	items := make([]*Item, 0, len(src.Items))
+	for _, s := range src.Items {
+		d, ok := dstItems[s.Id]
+		if !ok {
+			d = &Item{Id: s.Id}
+		}
+		d.copyCatalog(s)
+		items = append(items, d)
+	}
+	dst.Items = items
+	if src.LastId > dst.LastId {
+		dst.LastId = src.LastId
+	}
+	if dst.CategoriesString != src.CategoriesString {
+		dst.CategoriesString = src.CategoriesString
+		dst.orderFunc = nil
+	}
+	dst.createUniqueNames()
+	dst.Order()
+}
+
+// copyCatalog copies the data describing the item, but not the data
+// describing the state of the item in a specific list.
+func (i *Item) copyCatalog(o *Item) {
+	i.Name = o.Name
+	i.Shops = o.Shops
+	i.UnitDef = o.UnitDef
+	i.unitCreated = false
+	i.Weight = o.Weight
+	i.WeightStr = o.WeightStr
+	i.Volume = o.Volume
+	i.VolumeStr = o.VolumeStr
+	i.Category = o.Category
+	i.Tags = o.Tags
+	i.ShelfLife = o.ShelfLife
+}
+
+// All returns all lists of the account
+func (a *Account) All() []*ListData {
+	a.Named("")
+	l := make([]*ListData, len(a.Lists))
+	for i, nl := range a.Lists {
+		l[i] = nl.List
+	}
+	return l
+}
+
+// Save writes the account
+func (a *Account) Save(w io.Writer) error {
+	return json.NewEncoder(w).Encode(a)
+}
+
+// LoadAccount loads an account. If the data contains a single list as
+// written by older versions, an account containing only this list is created.
+func LoadAccount(r io.Reader) (*Account, error) {
+	b, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	var probe struct {
+		Lists json.RawMessage
+	}
+	err = json.Unmarshal(b, &probe)
+	if err != nil {
+		return nil, err
+	}
+
+	var a Account
+	if probe.Lists == nil {
+		var ld ListData
+		err = json.Unmarshal(b, &ld)
+		if err != nil {
+			return nil, err
+		}
+		a.Lists = []*NamedList{{Name: defaultListName, List: &ld}}
+	} else {
+		err = json.Unmarshal(b, &a)
+		if err != nil {
+			return nil, err
+		}
+	}
+	for _, ld := range a.All() {
+		ld.loaded()
+	}
+	return &a, nil
+}
@@ -0,0 +1,104 @@
+package item
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestLoadAccount_Legacy(t *testing.T) {
+	a, err := LoadAccount(bytes.NewBufferString(`{"Items":[{"Id":1,"Name":"Milch","QuantityRequired":2}]}`))
+	assert.NoError(t, err)
+	assert.EqualValues(t, 1, len(a.Lists))
+	assert.EqualValues(t, defaultListName, a.Named("").Name)
+	assert.EqualValues(t, 2, a.Named("").List.ItemById(1).QuantityRequired)
+
+	var buf bytes.Buffer
+	assert.NoError(t, a.Save(&buf))
+	b, err := LoadAccount(&buf)
+	assert.NoError(t, err)
+	assert.EqualValues(t, 1, len(b.Lists))
+	assert.EqualValues(t, "Milch", b.Named("").List.ItemById(1).Name)
+}
+
+func TestAccount_Lists(t *testing.T) {
+	a := Account{}
+	home := a.Named("")
+	home.List.AddItem(&Item{Name: "Milch", Category: "Kühlregal"})
+	home.List.SetQuantity(1, 2)
+
+	assert.NoError(t, a.AddList("Büro", false, home))
+	assert.NoError(t, a.AddList("Ferienwohnung", true, home))
+	assert.Error(t, a.AddList("Büro", false, home))
+	assert.Error(t, a.AddList(" ", false, home))
+	assert.EqualValues(t, 3, len(a.Lists))
+
+	assert.EqualValues(t, 0, len(a.Lists[1].List.Items))
+	assert.True(t, home.Shared)
+	fw := a.Named("Ferienwohnung")
+	assert.EqualValues(t, 1, len(fw.List.Items))
+	assert.EqualValues(t, "Milch", fw.List.ItemById(1).Name)
+	assert.EqualValues(t, 0, fw.List.ItemById(1).QuantityRequired)
+
+	fw.List.SetQuantity(1, 5)
+	fw.List.AddItem(&Item{Name: "Butter", Category: "Kühlregal"})
+	a.SyncCatalog(fw)
+	assert.EqualValues(t, 2, len(home.List.Items))
+	assert.EqualValues(t, 2, home.List.ItemById(1).QuantityRequired)
+	assert.EqualValues(t, 0, len(a.Named("Büro").List.Items))
+	assert.Same(t, home, a.Named("unbekannt"))
+
+	a.DeleteList("Büro")
+	a.DeleteList("Büro")
+	assert.EqualValues(t, 2, len(a.Lists))
+	assert.Nil(t, a.ListByName("Büro"))
+	a.DeleteList(defaultListName)
+	a.DeleteList("Ferienwohnung")
+	assert.EqualValues(t, 1, len(a.Lists))
+	assert.EqualValues(t, "Ferienwohnung", a.Named("").Name)
+}
+
+func TestAccount_SharedIds(t *testing.T) {
+	a := &Account{}
+	home := a.Named("")
+	home.List.AddItem(&Item{Name: "Milch"})
+	home.List.AddItem(&Item{Name: "Brot"})
+	assert.NoError(t, a.AddList("Ferienwohnung", true, home))
+
+	home.List.DeleteItem(2)
+	a.SyncCatalog(home)
+	fw := a.Named("Ferienwohnung")
+	fw.List.AddItem(&Item{Name: "Butter"})
+	a.SyncCatalog(fw)
+
+	for _, ld := range a.All() {
+		assert.Nil(t, ld.ItemById(2))
+		assert.EqualValues(t, "Butter", ld.ItemById(3).Name)
+	}
+}
+
+func TestAccount_AddSharedListFromUnsharedList(t *testing.T) {
+	a := &Account{}
+	home := a.Named("")
+	home.List.AddItem(&Item{Name: "Milch"})
+	home.List.AddItem(&Item{Name: "Brot"})
+	home.List.SetQuantity(2, 3)
+	assert.NoError(t, a.AddList("B", true, home))
+	assert.NoError(t, a.AddList("C", false, home))
+	c := a.Named("C")
+	c.List.AddItem(&Item{Name: "Schrauben"})
+
+	assert.Error(t, a.AddList("D", true, c), "the catalog of C uses other ids than the shared catalog")
+	assert.Nil(t, a.ListByName("D"))
+	assert.False(t, c.Shared)
+
+	a.SyncCatalog(c)
+	for _, name := range []string{defaultListName, "B"} {
+		ld := a.Named(name).List
+		assert.EqualValues(t, 2, len(ld.Items), name)
+		assert.EqualValues(t, "Milch", ld.ItemById(1).Name, name)
+		assert.EqualValues(t, "Brot", ld.ItemById(2).Name, name)
+	}
+	assert.EqualValues(t, 3, home.List.ItemById(2).QuantityRequired)
+	assert.EqualValues(t, 1, len(c.List.Items))
+}
@@ -0,0 +1,181 @@
+package item
+
+import (
+	"fmt"
+	"github.com/hneemann/shopping/metrics"
+	"log/slog"
+	"math"
+	"time"
+)
+
+// AuditAction is the kind of change recorded in the audit trail
+type AuditAction string
+
+const (
+	AuditCreate       AuditAction = "create"
+	AuditEdit         AuditAction = "edit"
+	AuditDelete       AuditAction = "delete"
+	AuditSetQuantity  AuditAction = "set"
+	AuditModQuantity  AuditAction = "mod"
+	AuditRemove       AuditAction = "remove"
+	AuditInCar        AuditAction = "car"
+	AuditOutOfCar     AuditAction = "uncar"
+	AuditNotAvailable AuditAction = "na"
+	AuditAvailable    AuditAction = "avail"
+	AuditTemp         AuditAction = "temp"
+	AuditPaid         AuditAction = "paid"
+	AuditAutoPaid     AuditAction = "autoPaid"
+	AuditRevert       AuditAction = "revert"
+	AuditTemplate     AuditAction = "template"
+	AuditMealPlan     AuditAction = "mealPlan"
+)
+
+const (
+	maxAuditEntries = 500
+	// auditMergeTime is the time in which repeated quantity changes of
+	// the same item by the same user are merged into a single entry
+	auditMergeTime = 5 * time.Minute
+)
+
+// listChanges counts the changes by action, e.g. the paid shopping trips
+// or the items put in the cart
+var listChanges = metrics.NewCounter("shopping_list_changes_total",
+	"Number of changes of the shopping lists by action.", "action")
+
+// AuditEntry is an entry of the audit trail of a list
+type AuditEntry struct {
+	Time     time.Time
+	User     string `json:",omitempty"`
+	Action   AuditAction
+	ItemId   int     `json:",omitempty"`
+	Name     string  `json:",omitempty"`
+	Quantity float64 `json:",omitempty"`
+}
+
+// Description describes the change in a human-readable way
+func (e AuditEntry) Description() string {
+	user := e.User
+	if user == "" {
+		user = "Jemand"
+	}
+	switch e.Action {
+	case AuditCreate:
+		return fmt.Sprintf("%s hat den Artikel %s angelegt", user, e.Name)
+	case AuditEdit:
+		return fmt.Sprintf("%s hat den Artikel %s bearbeitet", user, e.Name)
+	case AuditDelete:
+		return fmt.Sprintf("%s hat den Artikel %s gelöscht", user, e.Name)
+	case AuditSetQuantity:
+		return fmt.Sprintf("%s hat die Menge von %s auf %s gesetzt", user, e.Name, niceNumber(e.Quantity))
+	case AuditModQuantity:
+		if e.Quantity < 0 {
+			return fmt.Sprintf("%s hat die Menge von %s um %s verringert", user, e.Name, niceNumber(-e.Quantity))
+		}
+		return fmt.Sprintf("%s hat die Menge von %s um %s erhöht", user, e.Name, niceNumber(e.Quantity))
+	case AuditRemove:
+		return fmt.Sprintf("%s hat %s von der Liste gestrichen", user, e.Name)
+	case AuditInCar:
+		return fmt.Sprintf("%s hat %s in den Einkaufswagen gelegt", user, e.Name)
+	case AuditOutOfCar:
+		return fmt.Sprintf("%s hat %s aus dem Einkaufswagen genommen", user, e.Name)
+	case AuditNotAvailable:
+		return fmt.Sprintf("%s hat %s als ausverkauft markiert", user, e.Name)
+	case AuditAvailable:
+		return fmt.Sprintf("%s hat %s wieder als verfügbar markiert", user, e.Name)
+	case AuditTemp:
+		return fmt.Sprintf("%s hat %s notiert", user, e.Name)
+	case AuditPaid:
+		return fmt.Sprintf("%s hat den Einkauf bezahlt", user)
+	case AuditAutoPaid:
+		return "Der Einkauf wurde automatisch als bezahlt markiert"
+	case AuditRevert:
+		return fmt.Sprintf("%s hat das automatische Bezahlen rückgängig gemacht", user)
+	case AuditTemplate:
+		return fmt.Sprintf("%s hat die Vorlage %s angewendet", user, e.Name)
+	case AuditMealPlan:
+		return fmt.Sprintf("%s hat die Zutaten des Wochenplans hinzugefügt", user)
+	default:
+		return fmt.Sprintf("%s: %s %s", user, e.Action, e.Name)
+	}
+}
+
+func niceNumber(v float64) string {
+	if math.Abs(math.Round(v)-v) < eps {
+		return fmt.Sprintf("%d", int(math.Round(v)))
+	}
+	return fmt.Sprintf("%.1f", v)
+}
+
+// SetActor sets the user who modifies the list and the name of the list.
+// Both are used in the audit trail and the log messages.
+func (ld *ListData) SetActor(user, list string) {
+	ld.actor = user
+	ld.listName = list
+}
+
+// Logger returns a logger which adds the user and the list to all messages
+func (ld *ListData) Logger() *slog.Logger {
+	return slog.Default().With("user", ld.actor, "list", ld.listName)
+}
+
+// audit records a change of an item
+func (ld *ListData) audit(action AuditAction, i *Item, quantity float64) {
+	if i == nil {
+		ld.auditName(action, 0, "", quantity)
+	} else {
+		ld.auditName(action, i.Id, i.Name, quantity)
+	}
+}
+
+// auditName records a change of the list
+func (ld *ListData) auditName(action AuditAction, id int, name string, quantity float64) {
+	ld.addAudit(AuditEntry{
+		Time:     time.Now(),
+		User:     ld.actor,
+		Action:   action,
+		ItemId:   id,
+		Name:     name,
+		Quantity: quantity,
+	})
+}
+
+func (ld *ListData) addAudit(e AuditEntry) {
+	attrs := []any{"action", string(e.Action)}
+	if e.ItemId != 0 {
+		attrs = append(attrs, "item", e.ItemId)
+	}
+	if e.Name != "" {
+		attrs = append(attrs, "name", e.Name)
+	}
+	if e.Quantity != 0 {
+		attrs = append(attrs, "quantity", e.Quantity)
+	}
+	slog.Default().With("user", e.User, "list", ld.listName).Info("list changed", attrs...)
+	listChanges.Inc(string(e.Action))
+	ld.changed()
+
+	if e.Action == AuditModQuantity && len(ld.Audit) > 0 {
+		last := &ld.Audit[len(ld.Audit)-1]
+		if last.Action == e.Action && last.ItemId == e.ItemId && last.User == e.User && e.Time.Sub(last.Time) < auditMergeTime {
+			last.Quantity += e.Quantity
+			last.Time = e.Time
+			return
+		}
+	}
+	ld.Audit = append(ld.Audit, e)
+	if len(ld.Audit) > maxAuditEntries {
+		ld.Audit = ld.Audit[len(ld.Audit)-maxAuditEntries:]
+	}
+}
+
+// RecentAudit returns the latest n entries of the audit trail, the newest first
+func (ld *ListData) RecentAudit(n int) []AuditEntry {
+	if n > len(ld.Audit) {
+		n = len(ld.Audit)
+	}
+	r := make([]AuditEntry, n)
+	for i := range r {
+		r[i] = ld.Audit[len(ld.Audit)-1-i]
+	}
+	return r
+}
@@ -0,0 +1,91 @@
+package item
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestAudit(t *testing.T) {
+	ld := &ListData{}
+	ld.SetActor("anna", "Zuhause")
+	ld.AddItem(New("Milch", "Packung", 1000, "", 1000, "", "", nil))
+	ld.ModQuantity(1, 1, false)
+	ld.ModQuantity(1, 2, false)
+	ld.ToggleInCar(1)
+	ld.SetActor("ben", "Zuhause")
+	ld.Paid()
+
+	assert.EqualValues(t, []string{
+		"anna hat den Artikel Milch angelegt",
+		"anna hat die Menge von Milch um 3 erhöht",
+		"anna hat Milch in den Einkaufswagen gelegt",
+		"ben hat den Einkauf bezahlt",
+	}, descriptions(ld.Audit))
+
+	recent := ld.RecentAudit(2)
+	assert.EqualValues(t, []string{
+		"ben hat den Einkauf bezahlt",
+		"anna hat Milch in den Einkaufswagen gelegt",
+	}, descriptions(recent))
+
+	var b bytes.Buffer
+	assert.NoError(t, ld.Save(&b))
+	var loaded ListData
+	assert.NoError(t, json.Unmarshal(b.Bytes(), &loaded))
+	assert.EqualValues(t, descriptions(ld.Audit), descriptions(loaded.Audit))
+}
+
+func TestAuditMerge(t *testing.T) {
+	ld := &ListData{}
+	now := time.Now()
+	ld.addAudit(AuditEntry{Time: now, User: "anna", Action: AuditModQuantity, ItemId: 1, Name: "Milch", Quantity: 1})
+	ld.addAudit(AuditEntry{Time: now.Add(time.Minute), User: "anna", Action: AuditModQuantity, ItemId: 1, Name: "Milch", Quantity: -3})
+	ld.addAudit(AuditEntry{Time: now.Add(time.Minute), User: "ben", Action: AuditModQuantity, ItemId: 1, Name: "Milch", Quantity: 1})
+	ld.addAudit(AuditEntry{Time: now.Add(time.Hour), User: "ben", Action: AuditModQuantity, ItemId: 1, Name: "Milch", Quantity: 1})
+	assert.EqualValues(t, []string{
+		"anna hat die Menge von Milch um 2 verringert",
+		"ben hat die Menge von Milch um 1 erhöht",
+		"ben hat die Menge von Milch um 1 erhöht",
+	}, descriptions(ld.Audit))
+}
+
+func TestAuditLimit(t *testing.T) {
+	ld := &ListData{}
+	for i := 0; i < maxAuditEntries+10; i++ {
+		ld.auditName(AuditTemp, 0, "Brot", 0)
+	}
+	assert.EqualValues(t, maxAuditEntries, len(ld.Audit))
+}
+
+func TestAuditAutoPaid(t *testing.T) {
+	ld := &ListData{}
+	ld.SetActor("anna", "Zuhause")
+	ld.AddItem(New("Milch", "Packung", 1000, "", 1000, "", "", nil))
+	ld.SetQuantity(1, 2)
+	ld.ToggleInCar(1)
+	ld.SetPolicy(AutoPaidPolicy{Mode: AutoPaidIdle, IdleHours: 1})
+	assert.True(t, ld.CheckAutoPaid(ld.LastAddedToCar.Add(2*time.Hour)))
+
+	last := ld.Audit[len(ld.Audit)-1]
+	assert.EqualValues(t, AuditAutoPaid, last.Action)
+	assert.EqualValues(t, "", last.User)
+	assert.EqualValues(t, "Der Einkauf wurde automatisch als bezahlt markiert", last.Description())
+}
+
+func descriptions(entries []AuditEntry) []string {
+	var d []string
+	for _, e := range entries {
+		d = append(d, e.Description())
+	}
+	return d
+}
+
+func TestAuditMetrics(t *testing.T) {
+	paid := listChanges.Value(string(AuditPaid))
+	ld := &ListData{}
+	ld.Paid()
+	assert.EqualValues(t, paid+1, listChanges.Value(string(AuditPaid)))
+}
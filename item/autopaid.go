@@ -0,0 +1,284 @@
+package item
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// AutoPaidMode defines when items in the cart are automatically checked out
+type AutoPaidMode string
+
+const (
+	// AutoPaidOff disables the automatic checkout
+	AutoPaidOff AutoPaidMode = "off"
+	// AutoPaidIdle checks out if nothing was put in the cart for some hours
+	AutoPaidIdle AutoPaidMode = "idle"
+	// AutoPaidAt checks out at a fixed time of the day
+	AutoPaidAt AutoPaidMode = "at"
+)
+
+const defaultIdleHours = 8
+
+// AutoPaidPolicy configures the automatic checkout
+type AutoPaidPolicy struct {
+	Mode      AutoPaidMode
+	IdleHours int
+	// At is the time of the day formatted as "15:04"
+	At string
+}
+
+// Policy returns the configured policy or the default
+// policy if nothing is configured.
+func (ld *ListData) Policy() AutoPaidPolicy {
+	p := ld.AutoPaid
+	switch p.Mode {
+	case AutoPaidOff:
+	case AutoPaidAt:
+		if _, err := time.Parse("15:04", p.At); err != nil {
+			p.At = "00:00"
+		}
+	default:
+		p.Mode = AutoPaidIdle
+		if p.IdleHours <= 0 {
+			p.IdleHours = defaultIdleHours
+		}
+	}
+	return p
+}
+
+// SetPolicy sets the automatic checkout policy
+func (ld *ListData) SetPolicy(p AutoPaidPolicy) {
+	ld.changed()
+	ld.AutoPaid = p
+	ld.AutoPaid = ld.Policy()
+}
+
+// due returns true if a cart last filled at lastAdded is to be checked out at time now
+func (p AutoPaidPolicy) due(lastAdded, now time.Time) bool {
+	switch p.Mode {
+	case AutoPaidIdle:
+		return now.Sub(lastAdded) >= time.Duration(p.IdleHours)*time.Hour
+	case AutoPaidAt:
+		at, err := time.Parse("15:04", p.At)
+		if err != nil {
+			return false
+		}
+		t := time.Date(now.Year(), now.Month(), now.Day(), at.Hour(), at.Minute(), 0, 0, now.Location())
+		if t.After(now) {
+			t = t.AddDate(0, 0, -1)
+		}
+		return lastAdded.Before(t)
+	default:
+		return false
+	}
+}
+
+// AutoCheckout records an automatic checkout so that it can be reverted.
+// Only the state modified by the checkout is recorded, so that changes
+// made after the checkout are kept if the checkout is reverted.
+type AutoCheckout struct {
+	Time time.Time
+	// LastAddedToCar is the time the cart was filled last before the checkout
+	LastAddedToCar time.Time
+	Items          []CheckoutItem `json:",omitempty"`
+	// Temps are the temporary entries removed by the checkout
+	Temps []TempItem `json:",omitempty"`
+}
+
+// CheckoutItem is the state of an item before the automatic checkout
+type CheckoutItem struct {
+	Id               int
+	QuantityRequired float64
+	IsInCar          bool             `json:",omitempty"`
+	IsNotAvailable   bool             `json:",omitempty"`
+	RecipeQuantities []RecipeQuantity `json:",omitempty"`
+}
+
+// bought returns true if the item was checked out
+func (c CheckoutItem) bought() bool {
+	return c.IsInCar && c.QuantityRequired > 0
+}
+
+func (ld *ListData) isSomethingInCar() bool {
+	for _, item := range ld.Items {
+		if item.QuantityRequired > 0 && item.IsInCar {
+			return true
+		}
+	}
+	for _, item := range ld.TempItems {
+		if item.IsInCar {
+			return true
+		}
+	}
+	return false
+}
+
+// CheckAutoPaid checks out the cart if this is due according to the policy.
+// Returns true if a checkout took place.
+func (ld *ListData) CheckAutoPaid(now time.Time) bool {
+	if !ld.isSomethingInCar() || !ld.Policy().due(ld.LastAddedToCar, now) {
+		return false
+	}
+
+	ac := &AutoCheckout{Time: now, LastAddedToCar: ld.LastAddedToCar}
+	for _, item := range ld.Items {
+		if item.IsNotAvailable || (item.IsInCar && item.QuantityRequired > 0) {
+			ac.Items = append(ac.Items, CheckoutItem{
+				Id:               item.Id,
+				QuantityRequired: item.QuantityRequired,
+				IsInCar:          item.IsInCar,
+				IsNotAvailable:   item.IsNotAvailable,
+				RecipeQuantities: item.RecipeQuantities,
+			})
+		}
+	}
+	for _, t := range ld.TempItems {
+		if t.IsInCar {
+			ac.Temps = append(ac.Temps, t)
+		}
+	}
+
+	// the checkout is done by the scheduler, not by the user who used the list last
+	ld.addAudit(AuditEntry{Time: now, Action: AuditAutoPaid})
+	ld.paid()
+	ld.AutoCheckout = ac
+	return true
+}
+
+// RevertAutoPaid undoes the last automatic checkout. The recorded state is
+// applied to the items which still exist, all other items are left unchanged.
+func (ld *ListData) RevertAutoPaid() {
+	ac := ld.AutoCheckout
+	if ac == nil {
+		return
+	}
+	ld.AutoCheckout = nil
+
+	ld.audit(AuditRevert, nil, 0)
+	for _, c := range ac.Items {
+		item := ld.ItemById(c.Id)
+		if item == nil {
+			continue
+		}
+		if c.bought() {
+			item.unbuy(c.QuantityRequired, ac.LastAddedToCar)
+		}
+		item.QuantityRequired = c.QuantityRequired
+		item.IsInCar = c.IsInCar
+		item.IsNotAvailable = c.IsNotAvailable
+		item.RecipeQuantities = c.RecipeQuantities
+		item.suggestedQuantityCalculated = false
+	}
+	for _, t := range ac.Temps {
+		if ld.TempById(t.Id) == nil {
+			ld.TempItems = append(ld.TempItems, t)
+		}
+		ld.removeTempHistory(t.Name, ac.LastAddedToCar)
+	}
+	// the shopping is considered to be still in progress
+	ld.LastAddedToCar = time.Now()
+}
+
+// unbuy removes the purchase of quantity q at shopTime from the
+// shop history, the stock and the batches
+func (i *Item) unbuy(q float64, shopTime time.Time) {
+	for n := len(i.ShopHistory) - 1; n >= 0; n-- {
+		if e := i.ShopHistory[n]; e.ShopTime.Equal(shopTime) && math.Abs(e.Quantity-q) < eps {
+			i.ShopHistory = append(i.ShopHistory[:n], i.ShopHistory[n+1:]...)
+			break
+		}
+	}
+	if i.TrackStock {
+		i.Stock -= q
+		if i.Stock < 0.001 {
+			i.Stock = 0
+		}
+	}
+	if i.ShelfLife > 0 {
+		bestBefore := toDay(shopTime).AddDate(0, 0, i.ShelfLife)
+		for n := range i.Batches {
+			if b := &i.Batches[n]; b.BestBefore.Equal(bestBefore) {
+				b.Quantity -= q
+				if b.Quantity < eps {
+					i.Batches = append(i.Batches[:n], i.Batches[n+1:]...)
+				}
+				break
+			}
+		}
+	}
+}
+
+// removeTempHistory removes the purchase of a temporary entry from the history
+func (ld *ListData) removeTempHistory(name string, shopTime time.Time) {
+	for n := len(ld.TempHistory) - 1; n >= 0; n-- {
+		if e := ld.TempHistory[n]; e.Name == name && e.ShopTime.Equal(shopTime) {
+			ld.TempHistory = append(ld.TempHistory[:n], ld.TempHistory[n+1:]...)
+			return
+		}
+	}
+}
+
+// AcknowledgeAutoPaid removes the notice of an automatic checkout
+func (ld *ListData) AcknowledgeAutoPaid() {
+	ld.changed()
+	ld.AutoCheckout = nil
+}
+
+// Scheduler periodically evaluates the automatic checkout
+// policy of all lists of the accounts held in memory.
+type Scheduler struct {
+	mutex    sync.Mutex
+	accounts map[*Account]struct{}
+	shutDown chan struct{}
+}
+
+// NewScheduler creates a new scheduler which checks all lists in the given interval
+func NewScheduler(interval time.Duration) *Scheduler {
+	s := &Scheduler{
+		accounts: make(map[*Account]struct{}),
+		shutDown: make(chan struct{}),
+	}
+	go func() {
+		for {
+			select {
+			case <-time.After(interval):
+				s.check(time.Now())
+			case <-s.shutDown:
+				return
+			}
+		}
+	}()
+	return s
+}
+
+// Add adds an account to the scheduler
+func (s *Scheduler) Add(a *Account) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.accounts[a] = struct{}{}
+}
+
+// Remove removes an account from the scheduler
+func (s *Scheduler) Remove(a *Account) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	delete(s.accounts, a)
+}
+
+func (s *Scheduler) check(now time.Time) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	for a := range s.accounts {
+		a.Lock()
+		for _, ld := range a.All() {
+			ld.CheckAutoPaid(now)
+		}
+		a.Unlock()
+	}
+}
+
+// Close stops the scheduler
+func (s *Scheduler) Close() {
+	close(s.shutDown)
+}
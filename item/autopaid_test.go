@@ -0,0 +1,142 @@
+package item
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestAutoPaidPolicy_due(t *testing.T) {
+	at := func(h, m int) time.Time {
+		return time.Date(2025, 3, 10, h, m, 0, 0, time.Local)
+	}
+	tests := []struct {
+		name   string
+		policy AutoPaidPolicy
+		last   time.Time
+		now    time.Time
+		want   bool
+	}{
+		{"off", AutoPaidPolicy{Mode: AutoPaidOff}, at(1, 0), at(23, 0), false},
+		{"idle not yet", AutoPaidPolicy{Mode: AutoPaidIdle, IdleHours: 8}, at(1, 0), at(8, 59), false},
+		{"idle", AutoPaidPolicy{Mode: AutoPaidIdle, IdleHours: 8}, at(1, 0), at(9, 0), true},
+		{"idle after midnight", AutoPaidPolicy{Mode: AutoPaidIdle, IdleHours: 8}, at(23, 50).AddDate(0, 0, -1), at(0, 10), false},
+		{"at not yet", AutoPaidPolicy{Mode: AutoPaidAt, At: "04:00"}, at(1, 0), at(3, 59), false},
+		{"at", AutoPaidPolicy{Mode: AutoPaidAt, At: "04:00"}, at(1, 0), at(4, 0), true},
+		{"at yesterday", AutoPaidPolicy{Mode: AutoPaidAt, At: "04:00"}, at(3, 0).AddDate(0, 0, -1), at(3, 0), true},
+		{"at late shop", AutoPaidPolicy{Mode: AutoPaidAt, At: "04:00"}, at(23, 0).AddDate(0, 0, -1), at(1, 0), false},
+		{"at after", AutoPaidPolicy{Mode: AutoPaidAt, At: "04:00"}, at(5, 0), at(23, 0), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.EqualValues(t, tt.want, tt.policy.due(tt.last, tt.now))
+		})
+	}
+}
+
+func TestListData_Policy(t *testing.T) {
+	ld := ListData{}
+	assert.EqualValues(t, AutoPaidPolicy{Mode: AutoPaidIdle, IdleHours: defaultIdleHours}, ld.Policy())
+	ld.SetPolicy(AutoPaidPolicy{Mode: AutoPaidAt, At: "xx"})
+	assert.EqualValues(t, AutoPaidPolicy{Mode: AutoPaidAt, At: "00:00"}, ld.Policy())
+}
+
+func TestListData_CheckAutoPaid(t *testing.T) {
+	ld := ListData{}
+	ld.AddItem(&Item{Name: "Milch"})
+	ld.AddItem(&Item{Name: "Brot"})
+	ld.SetQuantity(1, 2)
+	ld.SetQuantity(2, 1)
+	ld.ToggleInCar(1)
+	ld.AddTemp("Kerzen", true)
+	ld.ToggleTemp(1)
+
+	assert.False(t, ld.CheckAutoPaid(time.Now()))
+	assert.True(t, ld.CheckAutoPaid(time.Now().Add(9*time.Hour)))
+	assert.NotNil(t, ld.AutoCheckout)
+	assert.EqualValues(t, 0, ld.ItemById(1).QuantityRequired)
+	assert.Len(t, ld.ItemById(1).ShopHistory, 1)
+	assert.Len(t, ld.TempItems, 0)
+
+	ld.RevertAutoPaid()
+	assert.Nil(t, ld.AutoCheckout)
+	milch := ld.ItemById(1)
+	assert.EqualValues(t, 2, milch.QuantityRequired)
+	assert.True(t, milch.IsInCar)
+	assert.Len(t, milch.ShopHistory, 0)
+	assert.EqualValues(t, 1, ld.ItemById(2).QuantityRequired)
+	assert.Len(t, ld.TempItems, 1)
+	assert.False(t, ld.CheckAutoPaid(time.Now().Add(time.Hour)))
+}
+
+func TestListData_RevertAutoPaidKeepsChanges(t *testing.T) {
+	ld := ListData{}
+	ld.AddItem(&Item{Name: "Milch", TrackStock: true, Stock: 1, ShelfLife: 7})
+	ld.AddItem(&Item{Name: "Eier"})
+	ld.SetQuantity(1, 2)
+	ld.SetQuantity(2, 1)
+	ld.ToggleInCar(1)
+	ld.ToggleAvailable(2)
+	ld.AddTemp("Kerzen", true)
+	ld.ToggleTemp(1)
+	recipe := ld.AddRecipe("Pfannkuchen", 2)
+	recipe.SetIngredient(1, 1)
+
+	assert.True(t, ld.CheckAutoPaid(time.Now().Add(9*time.Hour)))
+	milch := ld.ItemById(1)
+	assert.EqualValues(t, 3, milch.Stock)
+	assert.Len(t, milch.Batches, 1)
+	assert.Len(t, ld.TempHistory, 1)
+	assert.False(t, ld.ItemById(2).IsNotAvailable)
+
+	// changes made after the checkout
+	ld.AddItem(&Item{Name: "Brot"})
+	ld.SetQuantity(3, 1)
+	ld.Replace(2, &Item{Name: "Bio-Eier"})
+	ld.AddTemp("Servietten", true)
+
+	// the snapshot survives saving the list
+	var buf bytes.Buffer
+	assert.NoError(t, ld.Save(&buf))
+	loaded, err := Load(&buf)
+	assert.NoError(t, err)
+	loaded.RevertAutoPaid()
+
+	milch = loaded.ItemById(1)
+	assert.EqualValues(t, 2, milch.QuantityRequired)
+	assert.True(t, milch.IsInCar)
+	assert.Len(t, milch.ShopHistory, 0)
+	assert.EqualValues(t, 1, milch.Stock)
+	assert.Len(t, milch.Batches, 0)
+
+	eggs := loaded.ItemById(2)
+	assert.EqualValues(t, "Bio-Eier", eggs.Name)
+	assert.True(t, eggs.IsNotAvailable)
+
+	brot := loaded.ItemById(3)
+	if assert.NotNil(t, brot) {
+		assert.EqualValues(t, "Brot", brot.Name)
+		assert.EqualValues(t, 1, brot.QuantityRequired)
+	}
+	assert.EqualValues(t, 3, loaded.LastId)
+	assert.EqualValues(t, []Ingredient{{ItemId: 1, Quantity: 1}}, loaded.RecipeById(recipe.Id).Ingredients)
+
+	assert.Len(t, loaded.TempItems, 2)
+	assert.NotNil(t, loaded.TempById(1))
+	assert.True(t, loaded.TempById(1).IsInCar)
+	assert.Len(t, loaded.TempHistory, 0)
+}
+
+func TestListData_RevertAutoPaidDeletedItem(t *testing.T) {
+	ld := ListData{}
+	ld.AddItem(&Item{Name: "Milch"})
+	ld.SetQuantity(1, 2)
+	ld.ToggleInCar(1)
+	assert.True(t, ld.CheckAutoPaid(time.Now().Add(9*time.Hour)))
+
+	ld.DeleteItem(1)
+	ld.RevertAutoPaid()
+	assert.Len(t, ld.Items, 0)
+	assert.Nil(t, ld.AutoCheckout)
+}
@@ -0,0 +1,166 @@
+package item
+
+import (
+	"sort"
+	"time"
+)
+
+// Batch is a quantity of an item bought at once which has a best-before date
+type Batch struct {
+	Quantity   float64
+	BestBefore time.Time
+}
+
+// addBatch records a purchase if the item has a shelf life
+func (i *Item) addBatch(q float64, shopTime time.Time) {
+	if i.ShelfLife > 0 && q > 0 {
+		i.Batches = append(i.Batches, Batch{
+			Quantity:   q,
+			BestBefore: toDay(shopTime).AddDate(0, 0, i.ShelfLife),
+		})
+		i.sortBatches()
+	}
+}
+
+func (i *Item) sortBatches() {
+	sort.SliceStable(i.Batches, func(a, b int) bool {
+		return i.Batches[a].BestBefore.Before(i.Batches[b].BestBefore)
+	})
+}
+
+// consumeBatches removes the given quantity from the batches,
+// the batch which expires first is used first.
+func (i *Item) consumeBatches(q float64) {
+	for q > eps && len(i.Batches) > 0 {
+		b := &i.Batches[0]
+		if b.Quantity > q+eps {
+			b.Quantity -= q
+			return
+		}
+		q -= b.Quantity
+		i.Batches = i.Batches[1:]
+	}
+}
+
+// trimBatches removes the batches which expire first so that
+// the batches do not exceed the given total
+func (i *Item) trimBatches(total float64) {
+	sum := 0.0
+	for _, b := range i.Batches {
+		sum += b.Quantity
+	}
+	if sum > total {
+		i.consumeBatches(sum - total)
+	}
+}
+
+// ExpiringBatch is a batch shown in the "use soon" list
+type ExpiringBatch struct {
+	Item     *Item
+	Index    int
+	Batch    Batch
+	DaysLeft int
+}
+
+func (eb ExpiringBatch) Expired() bool {
+	return eb.DaysLeft < 0
+}
+
+// UseSoon returns all batches which expire within the given number of days,
+// the batch which expires first comes first.
+func (ld *ListData) UseSoon(days int) []ExpiringBatch {
+	today := toDay(time.Now())
+	limit := today.AddDate(0, 0, days)
+	var result []ExpiringBatch
+	for _, item := range ld.Items {
+		for n, b := range item.Batches {
+			if !b.BestBefore.After(limit) {
+				result = append(result, ExpiringBatch{
+					Item:     item,
+					Index:    n,
+					Batch:    b,
+					DaysLeft: daysBetween(today, toDay(b.BestBefore)),
+				})
+			}
+		}
+	}
+	sort.SliceStable(result, func(i, j int) bool {
+		return result[i].Batch.BestBefore.Before(result[j].Batch.BestBefore)
+	})
+	return result
+}
+
+func (ld *ListData) batch(id, n int) (*Item, bool) {
+	item := ld.ItemById(id)
+	if item == nil || n < 0 || n >= len(item.Batches) {
+		return nil, false
+	}
+	return item, true
+}
+
+// removeBatch removes the n-th batch and reduces the stock accordingly
+func (i *Item) removeBatch(n int) Batch {
+	b := i.Batches[n]
+	i.Batches = append(i.Batches[:n], i.Batches[n+1:]...)
+	if i.TrackStock {
+		i.Stock -= b.Quantity
+		if i.Stock < 0.001 {
+			i.Stock = 0
+		}
+		i.suggestedQuantityCalculated = false
+		i.checkMinStock()
+	}
+	return b
+}
+
+// ConsumeBatch marks the n-th batch of the item as used up
+func (ld *ListData) ConsumeBatch(id, n int) {
+	ld.changed()
+	if item, ok := ld.batch(id, n); ok {
+		b := item.removeBatch(n)
+		ld.Logger().Info("consumed batch", "item", item.Id, "name", item.Name, "quantity", b.Quantity)
+	}
+}
+
+// WasteBatch marks the n-th batch of the item as thrown away.
+// The wasted quantity is logged and reduces future suggestions.
+func (ld *ListData) WasteBatch(id, n int) {
+	ld.changed()
+	if item, ok := ld.batch(id, n); ok {
+		b := item.removeBatch(n)
+		ld.Logger().Info("wasted batch", "item", item.Id, "name", item.Name, "quantity", b.Quantity)
+		item.Waste = append(item.Waste, HistoryEntry{
+			ShopTime: time.Now(),
+			Quantity: b.Quantity,
+		})
+		item.suggestedQuantityCalculated = false
+	}
+}
+
+// SetBestBefore sets the best-before date of the n-th batch of the item
+func (ld *ListData) SetBestBefore(id, n int, bestBefore time.Time) {
+	ld.changed()
+	if item, ok := ld.batch(id, n); ok {
+		item.Batches[n].BestBefore = toDay(bestBefore)
+		item.sortBatches()
+	}
+}
+
+// wastedSince returns the quantity thrown away since the given time
+func (i *Item) wastedSince(t time.Time) float64 {
+	w := 0.0
+	for _, e := range i.Waste {
+		if e.ShopTime.After(t) {
+			w += e.Quantity
+		}
+	}
+	return w
+}
+
+func toDay(d time.Time) time.Time {
+	return time.Date(d.Year(), d.Month(), d.Day(), 0, 0, 0, 0, d.Location())
+}
+
+func daysBetween(a, b time.Time) int {
+	return int(b.Sub(a).Round(24*time.Hour) / (24 * time.Hour))
+}
@@ -0,0 +1,63 @@
+package item
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestListData_Expiry(t *testing.T) {
+	ld := ListData{}
+	ld.AddItem(&Item{Name: "Joghurt", ShelfLife: 10, TrackStock: true})
+	ld.AddItem(&Item{Name: "Salat", ShelfLife: 2})
+	ld.AddItem(&Item{Name: "Reis"})
+	for _, i := range ld.Items {
+		ld.SetQuantity(i.Id, 2)
+		ld.ToggleInCar(i.Id)
+	}
+	ld.Paid()
+
+	joghurt := ld.ItemById(1)
+	salat := ld.ItemById(2)
+	assert.Len(t, joghurt.Batches, 1)
+	assert.Len(t, salat.Batches, 1)
+	assert.Len(t, ld.ItemById(3).Batches, 0)
+
+	soon := ld.UseSoon(3)
+	assert.Len(t, soon, 1)
+	assert.EqualValues(t, "Salat", soon[0].Item.Name)
+	assert.EqualValues(t, 2, soon[0].DaysLeft)
+	assert.Len(t, ld.UseSoon(10), 2)
+
+	ld.SetBestBefore(1, 0, time.Now().AddDate(0, 0, -1))
+	soon = ld.UseSoon(0)
+	assert.Len(t, soon, 1)
+	assert.True(t, soon[0].Expired())
+
+	ld.Consume(1, 0.5)
+	assert.EqualValues(t, 1.5, joghurt.Batches[0].Quantity)
+	ld.WasteBatch(1, 0)
+	assert.Len(t, joghurt.Batches, 0)
+	assert.EqualValues(t, 0, joghurt.Stock)
+	assert.Len(t, joghurt.Waste, 1)
+	assert.EqualValues(t, 1.5, joghurt.Waste[0].Quantity)
+
+	ld.ConsumeBatch(2, 0)
+	assert.Len(t, salat.Batches, 0)
+	assert.Len(t, salat.Waste, 0)
+}
+
+func TestItem_SuggestWithWaste(t *testing.T) {
+	n := time.Now()
+	day := 24 * time.Hour
+	i := Item{ShopHistory: []HistoryEntry{
+		{n.Add(-8 * day), 2},
+		{n.Add(-6 * day), 2},
+		{n.Add(-4 * day), 2},
+		{n.Add(-2 * day), 2},
+	}}
+	withoutWaste := i.Suggest()
+	i.suggestedQuantityCalculated = false
+	i.Waste = []HistoryEntry{{n.Add(-3 * day), 3}}
+	assert.Less(t, i.Suggest(), withoutWaste)
+}
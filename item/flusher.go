@@ -0,0 +1,147 @@
+package item
+
+import (
+	"github.com/hneemann/shopping/metrics"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+var (
+	flushCount = metrics.NewCounter("shopping_flush_total",
+		"Number of modified accounts written to disk in the background by result.", "result")
+	flushDuration = metrics.NewHistogram("shopping_flush_duration_seconds",
+		"Duration of writing a modified account to disk in the background.", metrics.DefaultBuckets)
+)
+
+// dirty tracks the changes which are not yet written to disk
+type dirty struct {
+	dirtySince time.Time
+	changedAt  time.Time
+}
+
+// changed marks the data as modified
+func (d *dirty) changed() {
+	now := time.Now()
+	if d.dirtySince.IsZero() {
+		d.dirtySince = now
+	}
+	d.changedAt = now
+}
+
+func (d *dirty) merge(o dirty) {
+	if d.dirtySince.IsZero() || (!o.dirtySince.IsZero() && o.dirtySince.Before(d.dirtySince)) {
+		d.dirtySince = o.dirtySince
+	}
+	if o.changedAt.After(d.changedAt) {
+		d.changedAt = o.changedAt
+	}
+}
+
+// unsaved returns the modifications of the account and all its lists
+func (a *Account) unsaved() dirty {
+	d := a.dirty
+	for _, nl := range a.Lists {
+		d.merge(nl.List.dirty)
+	}
+	return d
+}
+
+// saved marks the account and all its lists as written to disk
+func (a *Account) saved() {
+	a.dirty = dirty{}
+	for _, nl := range a.Lists {
+		nl.List.dirty = dirty{}
+	}
+}
+
+// Modified returns true if the account contains changes not yet written to disk
+func (a *Account) Modified() bool {
+	return !a.unsaved().dirtySince.IsZero()
+}
+
+// Flusher periodically writes the modified accounts to disk, so that a crash
+// does not lose the changes made since the account was loaded.
+// To avoid writing on every tap, an account is written if it was not modified
+// for the debounce time, but at the latest after ten times the debounce time.
+type Flusher struct {
+	mutex    sync.Mutex
+	accounts map[*Account]func(*Account) error
+	debounce time.Duration
+	maxDelay time.Duration
+	shutDown chan struct{}
+}
+
+func newFlusher(debounce time.Duration) *Flusher {
+	return &Flusher{
+		accounts: make(map[*Account]func(*Account) error),
+		debounce: debounce,
+		maxDelay: 10 * debounce,
+		shutDown: make(chan struct{}),
+	}
+}
+
+// NewFlusher creates a new flusher with the given debounce time
+func NewFlusher(debounce time.Duration) *Flusher {
+	f := newFlusher(debounce)
+	go func() {
+		for {
+			select {
+			case <-time.After(debounce / 2):
+				f.check(time.Now())
+			case <-f.shutDown:
+				return
+			}
+		}
+	}()
+	return f
+}
+
+// Add adds an account to the flusher. The save function writes the account to disk.
+func (f *Flusher) Add(a *Account, save func(*Account) error) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	f.accounts[a] = save
+}
+
+// Remove removes an account from the flusher. If the account is
+// written at the moment, Remove waits until this is completed.
+func (f *Flusher) Remove(a *Account) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	delete(f.accounts, a)
+}
+
+func (f *Flusher) check(now time.Time) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	for a, save := range f.accounts {
+		f.checkAccount(a, save, now)
+	}
+}
+
+func (f *Flusher) checkAccount(a *Account, save func(*Account) error, now time.Time) {
+	a.Lock()
+	defer a.Unlock()
+	d := a.unsaved()
+	if d.dirtySince.IsZero() {
+		return
+	}
+	if now.Sub(d.changedAt) >= f.debounce || now.Sub(d.dirtySince) >= f.maxDelay {
+		start := time.Now()
+		err := save(a)
+		flushDuration.Since(start)
+		if err != nil {
+			flushCount.Inc("error")
+			slog.Error("could not save data", "user", a.User(), "err", err)
+			return
+		}
+		flushCount.Inc("ok")
+		a.saved()
+	}
+}
+
+// Close stops the flusher
+func (f *Flusher) Close() {
+	close(f.shutDown)
+}
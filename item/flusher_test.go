@@ -0,0 +1,120 @@
+package item
+
+import (
+	"bytes"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+// disk simulates the file an account is written to
+type disk struct {
+	data   []byte
+	writes int
+	err    error
+}
+
+func (d *disk) save(a *Account) error {
+	if d.err != nil {
+		return d.err
+	}
+	var b bytes.Buffer
+	err := a.Save(&b)
+	if err != nil {
+		return err
+	}
+	d.data = b.Bytes()
+	d.writes++
+	return nil
+}
+
+func TestFlusherSurvivesCrash(t *testing.T) {
+	const debounce = time.Second
+	var d disk
+	a := &Account{}
+	a.Named("").List.AddItem(New("Milch", "Packung", 1000, "", 1000, "", "", nil))
+	assert.NoError(t, d.save(a))
+	a.saved()
+
+	f := newFlusher(debounce)
+	f.Add(a, d.save)
+
+	f.check(time.Now())
+	assert.EqualValues(t, 1, d.writes, "nothing modified")
+
+	a.Named("").List.SetQuantity(1, 2)
+	a.Named("").List.ToggleInCar(1)
+	assert.True(t, a.Modified())
+	f.check(time.Now())
+	assert.EqualValues(t, 1, d.writes, "debounce time not elapsed")
+
+	f.check(time.Now().Add(debounce))
+	assert.EqualValues(t, 2, d.writes)
+	assert.False(t, a.Modified())
+
+	// the server crashes, only the data on disk is left
+	loaded, err := LoadAccount(bytes.NewReader(d.data))
+	assert.NoError(t, err)
+	milk := loaded.Named("").List.ItemById(1)
+	assert.EqualValues(t, 2, milk.QuantityRequired)
+	assert.True(t, milk.IsInCar)
+}
+
+func TestFlusherMaxDelay(t *testing.T) {
+	const debounce = time.Second
+	var d disk
+	a := &Account{}
+	f := newFlusher(debounce)
+	f.Add(a, d.save)
+
+	a.Named("").List.AddTemp("Brot", true)
+	start := a.unsaved().dirtySince
+	// continuous changes delay the write at most by maxDelay
+	a.dirty.changedAt = start.Add(9 * debounce)
+	f.check(start.Add(9*debounce + debounce/2))
+	assert.EqualValues(t, 0, d.writes)
+	f.check(start.Add(10 * debounce))
+	assert.EqualValues(t, 1, d.writes)
+}
+
+func TestFlusherError(t *testing.T) {
+	d := disk{err: errors.New("disk full")}
+	a := &Account{}
+	a.AddList("Büro", false, a.Named(""))
+	f := newFlusher(time.Second)
+	f.Add(a, d.save)
+
+	f.check(time.Now().Add(time.Minute))
+	assert.True(t, a.Modified(), "still modified after a failed write")
+
+	d.err = nil
+	f.check(time.Now().Add(time.Minute))
+	assert.False(t, a.Modified())
+	assert.EqualValues(t, 1, d.writes)
+
+	f.Remove(a)
+	a.DeleteList("Büro")
+	f.check(time.Now().Add(time.Minute))
+	assert.EqualValues(t, 1, d.writes, "removed accounts are not written")
+}
+
+func TestModifiedByListChanges(t *testing.T) {
+	a := &Account{}
+	ld := a.Named("").List
+	ld.AddItem(New("Milch", "Packung", 1000, "", 1000, "", "", nil))
+	a.saved()
+
+	for name, mod := range map[string]func(){
+		"quantity": func() { ld.ModQuantity(1, 1, false) },
+		"stock":    func() { ld.SetStock(1, 2) },
+		"recipe":   func() { ld.AddRecipe("Pfannkuchen", 2) },
+		"persons":  func() { ld.SetHouseholdSize(3) },
+		"template": func() { ld.CreateTemplate("Wochenende") },
+		"policy":   func() { ld.SetPolicy(AutoPaidPolicy{Mode: AutoPaidOff}) },
+	} {
+		mod()
+		assert.True(t, a.Modified(), name)
+		a.saved()
+	}
+}
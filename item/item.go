@@ -4,7 +4,6 @@ import (
 	"bytes"
 	"encoding/json"
 	"io"
-	"log"
 	"math"
 	"sort"
 	"strings"
@@ -16,10 +15,89 @@ import (
 const (
 	historyDays           = 180
 	daysShoppingHasToLast = 4
+	minTempRecurrence     = 2
 )
 
 type Category string
 
+// categorySeparator separates the levels of a nested category like "Kühlregal > Milchprodukte"
+const categorySeparator = ">"
+
+// Path returns the levels of the category
+func (c Category) Path() []string {
+	p := strings.Split(string(c), categorySeparator)
+	for i, l := range p {
+		p[i] = strings.TrimSpace(l)
+	}
+	return p
+}
+
+// Name returns the last level of the category
+func (c Category) Name() string {
+	p := c.Path()
+	return p[len(p)-1]
+}
+
+// Depth returns the nesting depth of the category, zero for a top level category
+func (c Category) Depth() int {
+	return len(c.Path()) - 1
+}
+
+// Parent returns the enclosing category or an empty category if c is a top level category
+func (c Category) Parent() Category {
+	p := c.Path()
+	if len(p) <= 1 {
+		return ""
+	}
+	return joinCategory(p[:len(p)-1])
+}
+
+// Normalized returns the category with the levels separated like in the section headers
+func (c Category) Normalized() Category {
+	return joinCategory(c.Path())
+}
+
+func (c Category) String() string {
+	return string(c)
+}
+
+func joinCategory(path []string) Category {
+	return Category(strings.Join(path, " "+categorySeparator+" "))
+}
+
+// CategoryHeader is a section header shown in the lists
+type CategoryHeader struct {
+	Category Category
+	Name     string
+	Depth    int
+}
+
+// Headers returns the section headers that have to be shown if
+// an item of this category follows an item of the category last.
+func (c Category) Headers(last string) []CategoryHeader {
+	p := c.Path()
+	lp := Category(last).Path()
+	if last == "" {
+		lp = nil
+	}
+	common := 0
+	for common < len(p) && common < len(lp) && p[common] == lp[common] {
+		common++
+	}
+	if common == len(p) {
+		common = len(p) - 1
+	}
+	var headers []CategoryHeader
+	for i := common; i < len(p); i++ {
+		headers = append(headers, CategoryHeader{
+			Category: joinCategory(p[:i+1]),
+			Name:     p[i],
+			Depth:    i,
+		})
+	}
+	return headers
+}
+
 type CategoryList []Category
 
 var defaultCategories = "Obst/Gemüse; Kühlregal; Kuchen; Brot; Tee/Kaffee; Backzutaten; Cerealien; Konserven; Fertiggerichte; Hygiene; Getränke; Tiefkühl; Süßes; Anderes"
@@ -43,24 +121,48 @@ type HistoryEntry struct {
 }
 
 type TempItem struct {
+	Id      int
 	Name    string
 	IsInCar bool
 }
 
+// TempHistoryEntry records a temporary entry that was bought
+type TempHistoryEntry struct {
+	Name     string
+	ShopTime time.Time
+}
+
 type ListData struct {
 	Items            []*Item
 	CategoriesString string
 	TempItems        []TempItem
+	TempHistory      []TempHistoryEntry
+	Recipes          []*Recipe
+	MealPlan         []PlannedMeal
+	ListTemplates    []*ListTemplate
 	LastAddedToCar   time.Time
+	AutoPaid         AutoPaidPolicy
+	AutoCheckout     *AutoCheckout
+	// Persons is the size of the household
+	Persons int
+	// Audit is the audit trail of the list, the oldest entry first
+	Audit []AuditEntry
+	// LastId is the highest item id assigned so far. The ids of
+	// deleted items are not reused, so that stale links fail instead
+	// of addressing another item.
+	LastId int
+	// LastTempId is the highest id of a temporary entry assigned so far
+	LastTempId int
+	// LastRecipeId is the highest recipe id assigned so far
+	LastRecipeId int
+	// LastMealId is the highest id of a planned meal assigned so far
+	LastMealId int
 
 	orderFunc  func(Category) int
 	categories []Category
-}
-
-func sameDay(a, b time.Time) bool {
-	ay, am, ad := a.Date()
-	by, bm, bd := b.Date()
-	return ay == by && am == bm && ad == bd
+	actor      string
+	listName   string
+	dirty
 }
 
 type Total struct {
@@ -69,16 +171,18 @@ type Total struct {
 }
 
 func (ld *ListData) AddItem(item *Item) {
-	id := 0
+	// lists written by older versions don't contain the last id
 	for _, it := range ld.Items {
-		if it.Id > id {
-			id = it.Id
+		if it.Id > ld.LastId {
+			ld.LastId = it.Id
 		}
 	}
-	item.Id = id + 1
+	ld.LastId++
+	item.Id = ld.LastId
 	ld.Items = append(ld.Items, item)
 	ld.createUniqueNames()
 	ld.Order()
+	ld.audit(AuditCreate, item, item.QuantityRequired)
 }
 
 func (ld *ListData) DeleteItem(id int) {
@@ -89,8 +193,14 @@ func (ld *ListData) DeleteItem(id int) {
 		}
 	}
 	if index >= 0 {
-		log.Println("finally delete item", ld.Items[index].Name)
+		ld.audit(AuditDelete, ld.Items[index], 0)
 		ld.Items = append(ld.Items[:index], ld.Items[index+1:]...)
+		for _, r := range ld.Recipes {
+			r.removeItem(id)
+		}
+		for _, lt := range ld.ListTemplates {
+			lt.removeItem(id)
+		}
 		ld.createUniqueNames()
 		ld.Order()
 	}
@@ -119,7 +229,29 @@ func (ld *ListData) Replace(id int, edit *Item) {
 		if item.Id == id {
 			edit.Id = item.Id
 			edit.QuantityRequired = item.QuantityRequired
+			edit.IsInCar = item.IsInCar
+			edit.IsNotAvailable = item.IsNotAvailable
+			edit.ShopHistory = item.ShopHistory
+			edit.RecipeQuantities = item.RecipeQuantities
+			edit.Batches = item.Batches
+			edit.Waste = item.Waste
+			if edit.Recurrence != nil {
+				if edit.Recurrence.sameInterval(item.Recurrence) {
+					edit.Recurrence.NextDue = item.Recurrence.NextDue
+					edit.Recurrence.Day = item.Recurrence.Day
+				} else {
+					edit.Recurrence.start(item.ShopHistory)
+				}
+			}
+			if !edit.TrackStock {
+				edit.Stock = 0
+				edit.MinStock = 0
+			} else {
+				edit.trimBatches(edit.Stock)
+			}
+			edit.checkMinStock()
 			ld.Items[i] = edit
+			ld.audit(AuditEdit, edit, 0)
 		}
 	}
 	ld.createUniqueNames()
@@ -156,7 +288,11 @@ func (ld *ListData) ToggleInCar(id int) {
 				item.IsNotAvailable = false
 				ld.LastAddedToCar = time.Now()
 			}
-			log.Println("in car:", item.Name, item.IsInCar)
+			if item.IsInCar {
+				ld.audit(AuditInCar, item, 0)
+			} else {
+				ld.audit(AuditOutOfCar, item, 0)
+			}
 		}
 	}
 }
@@ -168,68 +304,63 @@ func (ld *ListData) ToggleAvailable(id int) {
 			if item.IsNotAvailable {
 				item.IsInCar = false
 			}
-			log.Println("not available:", item.Name, item.IsInCar)
+			if item.IsNotAvailable {
+				ld.audit(AuditNotAvailable, item, 0)
+			} else {
+				ld.audit(AuditAvailable, item, 0)
+			}
 		}
 	}
 }
 
 func (ld *ListData) DeleteFromList(id int) {
 	if item := ld.ItemById(id); item != nil {
-		log.Println("deleted", item.Name)
+		ld.audit(AuditRemove, item, 0)
 		item.QuantityRequired = 0
 		item.IsInCar = false
 		item.IsNotAvailable = false
+		item.RecipeQuantities = nil
 	}
 }
 
-func (ld *ListData) checkPaidTimeout() {
-	if sameDay(ld.LastAddedToCar, time.Now()) {
-		return
-	}
-
-	isSomethingInCar := false
-	for _, item := range ld.Items {
-		if item.QuantityRequired > 0 && item.IsInCar {
-			isSomethingInCar = true
-			break
-		}
-	}
-	if !isSomethingInCar {
-		for _, item := range ld.TempItems {
-			if item.IsInCar {
-				isSomethingInCar = true
-				break
-			}
-		}
-	}
-
-	if isSomethingInCar {
-		log.Println("paid-timeout detected")
-		ld.Paid()
-	}
+func (ld *ListData) Paid() {
+	ld.audit(AuditPaid, nil, 0)
+	ld.paid()
 }
 
-func (ld *ListData) Paid() {
-	log.Println("Paid")
+func (ld *ListData) paid() {
+	ld.AutoCheckout = nil
 	for _, item := range ld.Items {
 		item.IsNotAvailable = false
 		if item.QuantityRequired > 0 {
 			if item.IsInCar {
+				if item.TrackStock {
+					item.Stock += item.QuantityRequired
+				}
+				item.addBatch(item.QuantityRequired, ld.LastAddedToCar)
 				item.ShopHistory = append(item.ShopHistory, HistoryEntry{
 					ShopTime: ld.LastAddedToCar,
 					Quantity: item.QuantityRequired,
 				})
 				item.QuantityRequired = 0
 				item.IsInCar = false
+				item.RecipeQuantities = nil
 				item.suggestedQuantityCalculated = false
 			}
 		}
 	}
-	for _, item := range ld.TempItems {
-		if item.IsInCar {
-			ld.TempItems = append(ld.TempItems[:0], ld.TempItems[1:]...)
+	var remaining []TempItem
+	for _, t := range ld.TempItems {
+		if t.IsInCar {
+			ld.TempHistory = append(ld.TempHistory, TempHistoryEntry{
+				Name:     t.Name,
+				ShopTime: ld.LastAddedToCar,
+			})
+		} else {
+			remaining = append(remaining, t)
 		}
 	}
+	ld.TempItems = remaining
 }
 
 func (ld *ListData) SetQuantity(id int, q float64) {
@@ -238,24 +369,30 @@ func (ld *ListData) SetQuantity(id int, q float64) {
 			q = 0
 		}
 		item.SetQuantity(q)
+		ld.audit(AuditSetQuantity, item, q)
 	}
 }
 
 func (ld *ListData) ModQuantity(id int, n float64, useUnitIncrement bool) {
 	if item := ld.ItemById(id); item != nil {
-		log.Println("mod quantity", item.Name, n)
 		f := 1.0
 		if useUnitIncrement {
 			f = item.Increment()
 		}
-		item.QuantityRequired += n * f
-		if item.QuantityRequired < 0.001 {
-			log.Println("negative quantity avoided", item.Name)
-			item.QuantityRequired = 0
-		}
-		item.IsInCar = false
-		item.IsNotAvailable = false
+		ld.audit(AuditModQuantity, item, n*f)
+		ld.modQuantity(item, n*f)
+	}
+}
+
+func (ld *ListData) modQuantity(item *Item, q float64) {
+	item.QuantityRequired += q
+	if item.QuantityRequired < 0.001 {
+		ld.Logger().Debug("negative quantity avoided", "item", item.Id, "name", item.Name)
+		item.QuantityRequired = 0
+		item.RecipeQuantities = nil
 	}
+	item.IsInCar = false
+	item.IsNotAvailable = false
 }
 
 func (ld *ListData) SomethingHidden() bool {
@@ -272,14 +409,19 @@ func (ld *ListData) SomethingHidden() bool {
 	return false
 }
 
+// MapOrder returns a function which maps a category to its position in the given list.
+// A nested category which is not in the list is placed at the position of its parent.
 func MapOrder(str []Category) func(Category) int {
 	m := make(map[Category]int)
 	for i, s := range str {
-		m[s] = i
+		m[joinCategory(s.Path())] = i
 	}
 	return func(c Category) int {
-		if i, ok := m[c]; ok {
-			return i
+		for c != "" {
+			if i, ok := m[joinCategory(c.Path())]; ok {
+				return i
+			}
+			c = c.Parent()
 		}
 		return len(m)
 	}
@@ -294,13 +436,14 @@ func (ld *ListData) initCategories() {
 		}
 		ld.categories = make([]Category, len(l))
 		for i, c := range l {
-			ld.categories[i] = Category(strings.TrimSpace(c))
+			ld.categories[i] = joinCategory(Category(c).Path())
 		}
 		ld.orderFunc = MapOrder(ld.categories)
 	}
 }
 
 func (ld *ListData) SetCategoryString(cat string) {
+	ld.changed()
 	ld.CategoriesString = cat
 	ld.orderFunc = nil
 	ld.initCategories()
@@ -340,6 +483,28 @@ func (ld *ListData) Shops() []string {
 	return result
 }
 
+// Tags returns all tags used by the items. Tags which only differ
+// in case are returned once, like they are matched by HasTag.
+func (ld *ListData) Tags() []string {
+	tags := make(map[string]string)
+	for _, item := range ld.Items {
+		for _, t := range item.Tags {
+			key := strings.ToLower(t)
+			if _, ok := tags[key]; !ok {
+				tags[key] = t
+			}
+		}
+	}
+	var result []string
+	for _, tag := range tags {
+		result = append(result, tag)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return germanLower(result[i]) < germanLower(result[j])
+	})
+	return result
+}
+
 func (ld *ListData) createUniqueNames() {
 	names := make(map[string]*[]*Item)
 	for _, item := range ld.Items {
@@ -360,8 +525,16 @@ func (ld *ListData) createUniqueNames() {
 	}
 }
 
+// historyRetention is the time the shopping history is kept
+var historyRetention = time.Hour * 24 * historyDays
+
+// SetHistoryRetention sets the time the shopping history is kept
+func SetHistoryRetention(d time.Duration) {
+	historyRetention = d
+}
+
 func (ld *ListData) removeOldHistory() {
-	cutTime := time.Now().Add(-time.Hour * 24 * historyDays)
+	cutTime := time.Now().Add(-historyRetention)
 	for _, item := range ld.Items {
 		removed := 0
 		for len(item.ShopHistory) > 0 {
@@ -372,22 +545,139 @@ func (ld *ListData) removeOldHistory() {
 				break
 			}
 		}
+		for len(item.Waste) > 0 && item.Waste[0].ShopTime.Before(cutTime) {
+			item.Waste = item.Waste[1:]
+		}
 		if removed > 0 {
-			log.Println("removed", removed, "old entries from", item.Name)
+			ld.Logger().Debug("removed old history entries", "item", item.Id, "name", item.Name, "removed", removed)
 		}
 	}
+	for len(ld.TempHistory) > 0 && ld.TempHistory[0].ShopTime.Before(cutTime) {
+		ld.TempHistory = ld.TempHistory[1:]
+	}
 }
 
-func (ld *ListData) AddTemp(name string) {
+// AddTemp adds a temporary entry. If force is false and there are items
+// which match the given name, nothing is added and the matching items are returned
+// so that the user can choose to increment one of them instead.
+func (ld *ListData) AddTemp(name string, force bool) []*Item {
 	name = strings.TrimSpace(name)
 	if len(name) > 0 {
-		ld.TempItems = append(ld.TempItems, TempItem{Name: name, IsInCar: false})
+		if !force {
+			if matches := ld.MatchItems(name); len(matches) > 0 {
+				return matches
+			}
+		}
+		ld.addTemp(name)
+		ld.auditName(AuditTemp, 0, name, 0)
 	}
+	return nil
 }
 
-func (ld *ListData) ToggleTemp(n int) {
-	if n >= 0 && n < len(ld.TempItems) {
-		ld.TempItems[n].IsInCar = !ld.TempItems[n].IsInCar
+func (ld *ListData) addTemp(name string) {
+	ld.LastTempId++
+	ld.TempItems = append(ld.TempItems, TempItem{Id: ld.LastTempId, Name: name})
+}
+
+// initTempIds assigns ids to the temporary entries written by older versions
+func (ld *ListData) initTempIds() {
+	for i := range ld.TempItems {
+		if ld.TempItems[i].Id > ld.LastTempId {
+			ld.LastTempId = ld.TempItems[i].Id
+		}
+	}
+	for i := range ld.TempItems {
+		if ld.TempItems[i].Id == 0 {
+			ld.LastTempId++
+			ld.TempItems[i].Id = ld.LastTempId
+		}
+	}
+}
+
+// TempById returns the temporary entry with the given id or nil if there is none
+func (ld *ListData) TempById(id int) *TempItem {
+	for i := range ld.TempItems {
+		if ld.TempItems[i].Id == id {
+			return &ld.TempItems[i]
+		}
+	}
+	return nil
+}
+
+// RemoveTemp removes the temporary entry with the given id.
+// It is used if a temporary entry is converted to an item.
+func (ld *ListData) RemoveTemp(id int) {
+	ld.changed()
+	for i, t := range ld.TempItems {
+		if t.Id == id {
+			ld.Logger().Debug("removed temp", "temp", id, "name", t.Name)
+			ld.TempItems = append(ld.TempItems[:i], ld.TempItems[i+1:]...)
+			return
+		}
+	}
+}
+
+// TempRecurrence describes a temporary entry that was bought repeatedly
+type TempRecurrence struct {
+	Name  string
+	Count int
+	Last  time.Time
+}
+
+// RecurringTemps returns the temporary entries which were bought on several
+// shopping trips and for which no item exists. These are candidates to
+// become regular items.
+func (ld *ListData) RecurringTemps() []TempRecurrence {
+	items := make(map[string]struct{})
+	for _, item := range ld.Items {
+		items[germanLower(item.Name)] = struct{}{}
+	}
+
+	m := make(map[string]*TempRecurrence)
+	var result []*TempRecurrence
+	for _, th := range ld.TempHistory {
+		key := germanLower(strings.TrimSpace(th.Name))
+		if _, ok := items[key]; ok {
+			continue
+		}
+		tr, ok := m[key]
+		if !ok {
+			tr = &TempRecurrence{}
+			m[key] = tr
+			result = append(result, tr)
+		}
+		if !tr.Last.Equal(th.ShopTime) {
+			tr.Count++
+		}
+		tr.Name = th.Name
+		tr.Last = th.ShopTime
+	}
+
+	var recurring []TempRecurrence
+	for _, tr := range result {
+		if tr.Count >= minTempRecurrence {
+			recurring = append(recurring, *tr)
+		}
+	}
+	sort.Slice(recurring, func(i, j int) bool {
+		if recurring[i].Count == recurring[j].Count {
+			return germanLower(recurring[i].Name) < germanLower(recurring[j].Name)
+		}
+		return recurring[i].Count > recurring[j].Count
+	})
+	return recurring
+}
+
+// ToggleTemp puts the temporary entry with the given id in the cart or takes it out
+func (ld *ListData) ToggleTemp(id int) {
+	if t := ld.TempById(id); t != nil {
+		t.IsInCar = !t.IsInCar
+		if t.IsInCar {
+			ld.LastAddedToCar = time.Now()
+			ld.auditName(AuditInCar, 0, t.Name, 0)
+		} else {
+			ld.auditName(AuditOutOfCar, 0, t.Name, 0)
+		}
 	}
 }
 
@@ -410,7 +700,16 @@ type Item struct {
 	Volume                      int
 	VolumeStr                   string
 	Category                    Category
+	Tags                        []string
 	ShopHistory                 []HistoryEntry
+	RecipeQuantities            []RecipeQuantity
+	TrackStock                  bool
+	Stock                       float64
+	MinStock                    float64
+	ShelfLife                   int
+	Batches                     []Batch
+	Waste                       []HistoryEntry
+	Recurrence                  *Recurrence
 	suggestedQuantityCalculated bool
 	suggestedQuantityRequired   float64
 }
@@ -436,10 +735,10 @@ func (i *Item) UniqueName() string {
 }
 
 func (i *Item) SetQuantity(quantity float64) {
-	log.Println("Set quantity", i.Name, quantity)
 	i.QuantityRequired = quantity
 	i.IsInCar = false
 	i.IsNotAvailable = false
+	i.RecipeQuantities = nil
 }
 
 func (i *Item) Less(other *Item, cat func(Category) int) bool {
@@ -450,7 +749,11 @@ func (i *Item) Less(other *Item, cat func(Category) int) bool {
 		return germanLower(i.Name) < germanLower(other.Name)
 	}
 	if cat != nil {
-		return cat(i.Category) < cat(other.Category)
+		ci := cat(i.Category)
+		co := cat(other.Category)
+		if ci != co {
+			return ci < co
+		}
 	}
 	return germanLower(string(i.Category)) < germanLower(string(other.Category))
 }
@@ -499,6 +802,24 @@ func (i *Item) ShopsStr() string {
 	return str
 }
 
+// HasTag returns true if the item is tagged with the given tag.
+// The empty tag matches all items.
+func (i *Item) HasTag(tag string) bool {
+	if tag == "" {
+		return true
+	}
+	for _, t := range i.Tags {
+		if strings.EqualFold(t, tag) {
+			return true
+		}
+	}
+	return false
+}
+
+func (i *Item) TagsStr() string {
+	return strings.Join(i.Tags, ", ")
+}
+
 func (i *Item) IsHidden() bool {
 	return i.IsInCar || i.IsNotAvailable
 }
@@ -506,29 +827,12 @@ func (i *Item) IsHidden() bool {
 func (i *Item) Suggest() float64 {
 	if !i.suggestedQuantityCalculated {
 		i.suggestedQuantityCalculated = true
-		if len(i.ShopHistory) > 2 {
-			maxEverAdded := 0.0
-			count := 0.0
-			lastCount := 0.0
-			for _, entry := range i.ShopHistory {
-				count += lastCount
-				lastCount = entry.Quantity
-				if lastCount > maxEverAdded {
-					maxEverAdded = lastCount
-				}
-			}
-			first := i.ShopHistory[0].ShopTime
-			last := i.ShopHistory[len(i.ShopHistory)-1].ShopTime
-
-			timePerItem := last.Sub(first) / time.Duration(count)
-			timeToPlan := time.Since(last) + time.Hour*24*daysShoppingHasToLast
-			suggestion := math.Round(timeToPlan.Hours()/timePerItem.Hours() - lastCount)
-			if suggestion < 0 {
-				suggestion = 0
-			} else if suggestion > maxEverAdded+1 {
-				suggestion = maxEverAdded + 1
-			}
-			i.suggestedQuantityRequired = suggestion
+		if i.TrackStock {
+			i.suggestedQuantityRequired = i.stockSuggestion()
+		} else if c, ok := i.consumption(); ok {
+			timeToPlan := time.Since(c.last) + time.Hour*24*daysShoppingHasToLast
+			suggestion := math.Round(timeToPlan.Hours()/c.hoursPerItem - c.lastCount)
+			i.suggestedQuantityRequired = c.limit(suggestion)
 		} else {
 			i.suggestedQuantityRequired = 0
 		}
@@ -536,6 +840,51 @@ func (i *Item) Suggest() float64 {
 	return i.suggestedQuantityRequired
 }
 
+// consumption describes the consumption of an item derived from its shopping history
+type consumption struct {
+	hoursPerItem float64
+	lastCount    float64
+	maxEverAdded float64
+	last         time.Time
+}
+
+func (c consumption) limit(suggestion float64) float64 {
+	if suggestion < 0 {
+		return 0
+	} else if suggestion > c.maxEverAdded+1 {
+		return c.maxEverAdded + 1
+	}
+	return suggestion
+}
+
+func (i *Item) consumption() (consumption, bool) {
+	if len(i.ShopHistory) <= 2 {
+		return consumption{}, false
+	}
+	maxEverAdded := 0.0
+	count := 0.0
+	lastCount := 0.0
+	for _, entry := range i.ShopHistory {
+		count += lastCount
+		lastCount = entry.Quantity
+		if lastCount > maxEverAdded {
+			maxEverAdded = lastCount
+		}
+	}
+	first := i.ShopHistory[0].ShopTime
+	last := i.ShopHistory[len(i.ShopHistory)-1].ShopTime
+	count -= i.wastedSince(first)
+	if count <= 0 {
+		return consumption{}, false
+	}
+	return consumption{
+		hoursPerItem: last.Sub(first).Hours() / count,
+		lastCount:    lastCount,
+		maxEverAdded: maxEverAdded,
+		last:         last,
+	}, true
+}
+
 type HistoryDescription struct {
 	Count int
 	Last  time.Time
@@ -555,6 +904,47 @@ func (i *Item) HistoryDescription() HistoryDescription {
 	}
 }
 
+// LastQuantity returns the quantity bought the last time
+func (i *Item) LastQuantity() float64 {
+	if i == nil || len(i.ShopHistory) == 0 {
+		return 0
+	}
+	return i.ShopHistory[len(i.ShopHistory)-1].Quantity
+}
+
+// HouseholdSize returns the number of persons in the household, at least one
+func (ld *ListData) HouseholdSize() int {
+	if ld.Persons < 1 {
+		return 1
+	}
+	return ld.Persons
+}
+
+// SetHouseholdSize sets the number of persons in the household
+func (ld *ListData) SetHouseholdSize(persons int) {
+	ld.changed()
+	if persons < 1 {
+		persons = 1
+	}
+	ld.Persons = persons
+}
+
+// QuantityVars returns the variables which can be used in an
+// expression describing the quantity of the given item.
+// The item is nil if a new item is created.
+func (ld *ListData) QuantityVars(i *Item) map[string]float64 {
+	vars := map[string]float64{
+		"persons": float64(ld.HouseholdSize()),
+		"suggest": 0,
+		"last":    0,
+	}
+	if i != nil {
+		vars["suggest"] = i.Suggest()
+		vars["last"] = i.LastQuantity()
+	}
+	return vars
+}
+
 func (i *Item) UnitSingular() string {
 	i.createUnits()
 	return i.unitSingular
@@ -595,6 +985,61 @@ var unitPluralMap = map[string]string{
 	"Box":     "Boxen",
 }
 
+// incrementUnits are the units which are changed in larger steps
+var incrementUnits = map[string]float64{
+	"g":    50,
+	"ml":   50,
+	"kg":   0.5,
+	"l":    0.5,
+	"kilo": 0.5,
+}
+
+// KnownUnit checks if the given word is a unit and returns its singular form.
+// Known are the units of unitPluralMap, the units with a special increment
+// and all units used by the items of the list.
+func (ld *ListData) KnownUnit(word string) (string, bool) {
+	lw := strings.ToLower(word)
+	if _, ok := incrementUnits[lw]; ok {
+		return lw, true
+	}
+	for s, p := range unitPluralMap {
+		if strings.EqualFold(s, word) || strings.EqualFold(p, word) {
+			return s, true
+		}
+	}
+	for _, item := range ld.Items {
+		if item.UnitDef != "" && (strings.EqualFold(item.UnitSingular(), word) || strings.EqualFold(item.UnitPlural(), word)) {
+			return item.UnitSingular(), true
+		}
+	}
+	return "", false
+}
+
+// FindItem returns the item with the given name. The name is compared to the
+// unique name of the items. If the name is not unique the unit is used to find
+// the item. Returns nil if there is no such item.
+func (ld *ListData) FindItem(name, unit string) *Item {
+	ln := germanLower(strings.TrimSpace(name))
+	for _, item := range ld.Items {
+		if germanLower(item.UniqueName()) == ln {
+			return item
+		}
+	}
+	key := matchKey(name)
+	var found *Item
+	for _, item := range ld.Items {
+		if matchKey(item.Name) == key {
+			if strings.EqualFold(item.UnitSingular(), unit) {
+				return item
+			}
+			if found == nil {
+				found = item
+			}
+		}
+	}
+	return found
+}
+
 func (i *Item) createUnits() {
 	if i.unitCreated {
 		return
@@ -655,14 +1100,10 @@ func shorten(s string) string {
 }
 
 func (i *Item) Increment() float64 {
-	f := 1.0
-	switch strings.ToLower(i.UnitDef) {
-	case "g", "ml":
-		f = 50
-	case "kg", "l", "kilo":
-		f = 0.5
+	if f, ok := incrementUnits[strings.ToLower(i.UnitDef)]; ok {
+		return f
 	}
-	return f
+	return 1
 }
 
 func Load(r io.Reader) (*ListData, error) {
@@ -672,9 +1113,17 @@ func Load(r io.Reader) (*ListData, error) {
 		return nil, err
 	}
 
-	items.removeOldHistory()
-	items.createUniqueNames()
-	items.checkPaidTimeout()
+	items.loaded()
 
 	return &items, nil
 }
+
+// loaded is called after a list is loaded
+func (ld *ListData) loaded() {
+	ld.initTempIds()
+	ld.initMealIds()
+	ld.removeOldHistory()
+	ld.createUniqueNames()
+	ld.checkRecurrences()
+	ld.checkMinStock()
+}
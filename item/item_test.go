@@ -1,7 +1,9 @@
 package item
 
 import (
+	"bytes"
 	"github.com/stretchr/testify/assert"
+	"strings"
 	"testing"
 	"time"
 )
@@ -69,3 +71,268 @@ func TestItems_removeOldHistory(t *testing.T) {
 		})
 	}
 }
+
+func TestCategory_Headers(t *testing.T) {
+	tests := []struct {
+		name string
+		cat  Category
+		last string
+		want []string
+	}{
+		{"first", "Obst", "", []string{"Obst"}},
+		{"first nested", "Kühlregal > Milch", "", []string{"Kühlregal", "Kühlregal > Milch"}},
+		{"sibling", "Kühlregal > Milch", "Kühlregal > Käse", []string{"Kühlregal > Milch"}},
+		{"child", "Kühlregal>Milch", "Kühlregal", []string{"Kühlregal > Milch"}},
+		{"other", "Brot", "Kühlregal > Milch", []string{"Brot"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, h := range tt.cat.Headers(tt.last) {
+				got = append(got, string(h.Category))
+			}
+			assert.EqualValues(t, tt.want, got)
+		})
+	}
+}
+
+func TestCategory_Normalized(t *testing.T) {
+	assert.EqualValues(t, "Kühlregal > Milch", Category("Kühlregal>Milch").Normalized())
+	assert.EqualValues(t, "Kühlregal > Milch", Category(" Kühlregal >  Milch").Normalized())
+	assert.EqualValues(t, "Obst", Category("Obst").Normalized())
+}
+
+func TestListData_Tags(t *testing.T) {
+	ld := &ListData{}
+	a := New("Äpfel", "kg", 0, "", 0, "", "Obst", nil)
+	a.Tags = []string{"Bio", "regional"}
+	ld.AddItem(a)
+	b := New("Milch", "l", 0, "", 0, "", "Kühlregal", nil)
+	b.Tags = []string{"bio", "Frühstück"}
+	ld.AddItem(b)
+
+	assert.EqualValues(t, []string{"bio", "Frühstück", "regional"}, ld.Tags())
+}
+
+func TestMapOrder(t *testing.T) {
+	o := MapOrder([]Category{"Obst", "Kühlregal", "Kühlregal > Milch", "Anderes"})
+	assert.EqualValues(t, 0, o("Obst"))
+	assert.EqualValues(t, 2, o("Kühlregal>Milch"))
+	assert.EqualValues(t, 1, o("Kühlregal > Käse"))
+	assert.EqualValues(t, 4, o("Unbekannt"))
+}
+
+func TestListData_RecurringTemps(t *testing.T) {
+	n := time.Now()
+	ld := ListData{
+		Items: []*Item{{Name: "Milch"}},
+		TempHistory: []TempHistoryEntry{
+			{"Kerzen", n.Add(-72 * time.Hour)},
+			{"Milch", n.Add(-72 * time.Hour)},
+			{"Grillkohle", n.Add(-72 * time.Hour)},
+			{"kerzen", n.Add(-48 * time.Hour)},
+			{"Milch", n.Add(-48 * time.Hour)},
+			{"Grillkohle", n.Add(-24 * time.Hour)},
+			{"Kerzen", n},
+			{"Servietten", n},
+			{"Servietten", n},
+		},
+	}
+	assert.EqualValues(t, []TempRecurrence{
+		{"Kerzen", 3, n},
+		{"Grillkohle", 2, n.Add(-24 * time.Hour)},
+	}, ld.RecurringTemps())
+}
+
+func TestListData_Replace(t *testing.T) {
+	ld := ListData{}
+	ld.AddItem(New("Milch", "Packung", 1000, "", 1000, "1l", "Kühlregal", []string{"Markt"}))
+	history := []HistoryEntry{{time.Now().AddDate(0, 0, -14), 2}, {time.Now().AddDate(0, 0, -7), 3}}
+	waste := []HistoryEntry{{time.Now().AddDate(0, 0, -2), 1}}
+	batches := []Batch{{Quantity: 2, BestBefore: toDay(time.Now().AddDate(0, 0, 3))}}
+	milk := ld.ItemById(1)
+	milk.ShopHistory = history
+	milk.Waste = waste
+	milk.Batches = batches
+	ld.SetQuantity(1, 2)
+	milk.RecipeQuantities = []RecipeQuantity{{"Pfannkuchen", 1}}
+	ld.ToggleInCar(1)
+
+	ld.Replace(1, &Item{Name: "Vollmilch", UnitDef: "Flasche", Category: "Kühlregal", TrackStock: true, Stock: 2, MinStock: 1,
+		ShelfLife: 7, Recurrence: NewRecurrence(1, Weeks, 1)})
+
+	m := ld.ItemById(1)
+	assert.EqualValues(t, "Vollmilch", m.Name)
+	assert.EqualValues(t, "Flasche", m.UnitDef)
+	assert.EqualValues(t, 2, m.QuantityRequired)
+	assert.True(t, m.IsInCar)
+	assert.EqualValues(t, history, m.ShopHistory)
+	assert.EqualValues(t, waste, m.Waste)
+	assert.EqualValues(t, batches, m.Batches)
+	assert.EqualValues(t, []RecipeQuantity{{"Pfannkuchen", 1}}, m.RecipeQuantities)
+	assert.EqualValues(t, 2, m.Stock)
+	assert.EqualValues(t, toDay(history[1].ShopTime.AddDate(0, 0, 7)), m.NextDue(), "recurrence starts at the last purchase")
+
+	// a second edit keeps everything
+	ld.ToggleAvailable(1)
+	ld.Replace(1, &Item{Name: "Vollmilch", UnitDef: "Flasche", Category: "Kühlregal", TrackStock: true, Stock: 2, MinStock: 1,
+		ShelfLife: 7, Recurrence: NewRecurrence(1, Weeks, 1)})
+	m = ld.ItemById(1)
+	assert.EqualValues(t, history, m.ShopHistory)
+	assert.True(t, m.IsNotAvailable)
+	assert.EqualValues(t, toDay(history[1].ShopTime.AddDate(0, 0, 7)), m.NextDue())
+}
+
+func TestListData_QuantityVars(t *testing.T) {
+	ld := ListData{}
+	ld.AddItem(New("Milch", "Packung", 0, "", 0, "", "", nil))
+	ld.ItemById(1).ShopHistory = []HistoryEntry{{ShopTime: time.Now(), Quantity: 3}}
+
+	assert.EqualValues(t, map[string]float64{"persons": 1, "suggest": 0, "last": 0}, ld.QuantityVars(nil))
+	ld.SetHouseholdSize(4)
+	vars := ld.QuantityVars(ld.ItemById(1))
+	assert.EqualValues(t, 4, vars["persons"])
+	assert.EqualValues(t, 3, vars["last"])
+	ld.SetHouseholdSize(0)
+	assert.EqualValues(t, 1, ld.HouseholdSize())
+}
+
+func TestListData_StableIds(t *testing.T) {
+	ld := &ListData{}
+	ld.AddItem(New("Milch", "Packung", 0, "", 0, "", "", nil))
+	ld.AddItem(New("Brot", "Stück", 0, "", 0, "", "", nil))
+	ld.DeleteItem(2)
+	ld.AddItem(New("Butter", "Stück", 0, "", 0, "", "", nil))
+	assert.Nil(t, ld.ItemById(2), "id of deleted item not reused")
+	assert.EqualValues(t, "Butter", ld.ItemById(3).Name)
+
+	var b bytes.Buffer
+	assert.NoError(t, ld.Save(&b))
+	loaded, err := Load(&b)
+	assert.NoError(t, err)
+	loaded.DeleteItem(3)
+	loaded.AddItem(New("Käse", "Stück", 0, "", 0, "", "", nil))
+	assert.EqualValues(t, "Käse", loaded.ItemById(4).Name)
+
+	// lists written by older versions contain no last id
+	loaded, err = Load(strings.NewReader(`{"Items":[{"Id":1,"Name":"Milch"},{"Id":7,"Name":"Brot"}]}`))
+	assert.NoError(t, err)
+	loaded.AddItem(New("Käse", "Stück", 0, "", 0, "", "", nil))
+	assert.EqualValues(t, "Käse", loaded.ItemById(8).Name)
+}
+
+func TestListData_TempIds(t *testing.T) {
+	ld := &ListData{}
+	ld.AddTemp("Kerzen", true)
+	ld.AddTemp("Servietten", true)
+	ld.AddTemp("Luftballons", true)
+	ld.RemoveTemp(1)
+	ld.AddTemp("Kerzen", true)
+	ld.AddTemp("Kerzen", true)
+	ld.RemoveTemp(4)
+
+	ld.ToggleTemp(3)
+	assert.EqualValues(t, []TempItem{
+		{Id: 2, Name: "Servietten"},
+		{Id: 3, Name: "Luftballons", IsInCar: true},
+		{Id: 5, Name: "Kerzen"},
+	}, ld.TempItems)
+	assert.Nil(t, ld.TempById(1))
+
+	// entries written by older versions get ids when loaded
+	loaded, err := Load(strings.NewReader(`{"TempItems":[{"Name":"Kerzen"},{"Name":"Servietten","IsInCar":true}]}`))
+	assert.NoError(t, err)
+	assert.EqualValues(t, 1, loaded.TempItems[0].Id)
+	assert.EqualValues(t, 2, loaded.TempItems[1].Id)
+	loaded.AddTemp("Luftballons", true)
+	assert.EqualValues(t, 3, loaded.TempItems[2].Id)
+}
+
+func TestListData_Paid(t *testing.T) {
+	type temp struct {
+		name  string
+		inCar bool
+	}
+	tests := []struct {
+		name      string
+		temps     []temp
+		remaining []string
+		bought    []string
+	}{
+		{name: "none"},
+		{name: "all in car", temps: []temp{{"a", true}, {"b", true}}, bought: []string{"a", "b"}},
+		{name: "none in car", temps: []temp{{"a", false}, {"b", false}}, remaining: []string{"a", "b"}},
+		{name: "first in car", temps: []temp{{"a", true}, {"b", false}, {"c", false}}, remaining: []string{"b", "c"}, bought: []string{"a"}},
+		{name: "last in car", temps: []temp{{"a", false}, {"b", false}, {"c", true}}, remaining: []string{"a", "b"}, bought: []string{"c"}},
+		{name: "mixed", temps: []temp{{"a", false}, {"b", true}, {"c", false}, {"d", true}, {"e", true}}, remaining: []string{"a", "c"}, bought: []string{"b", "d", "e"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ld := &ListData{}
+			for _, tmp := range tt.temps {
+				ld.AddTemp(tmp.name, true)
+				if tmp.inCar {
+					ld.ToggleTemp(ld.LastTempId)
+				}
+			}
+			ld.Paid()
+
+			var remaining []string
+			for _, tmp := range ld.TempItems {
+				assert.False(t, tmp.IsInCar)
+				remaining = append(remaining, tmp.Name)
+			}
+			assert.EqualValues(t, tt.remaining, remaining)
+			var bought []string
+			for _, th := range ld.TempHistory {
+				assert.EqualValues(t, ld.LastAddedToCar, th.ShopTime)
+				bought = append(bought, th.Name)
+			}
+			assert.EqualValues(t, tt.bought, bought)
+		})
+	}
+}
+
+func TestListData_PaidItems(t *testing.T) {
+	ld := &ListData{}
+	ld.AddItem(&Item{Name: "Milch", TrackStock: true, Stock: 1, ShelfLife: 7})
+	ld.AddItem(&Item{Name: "Brot"})
+	ld.AddItem(&Item{Name: "Hefe"})
+	ld.AddItem(&Item{Name: "Salz"})
+	ld.SetQuantity(1, 2)
+	ld.SetQuantity(2, 1)
+	ld.SetQuantity(3, 3)
+	ld.ItemById(1).RecipeQuantities = []RecipeQuantity{{Recipe: "Pfannkuchen", Quantity: 2}}
+	ld.ToggleInCar(1)
+	ld.ToggleAvailable(3)
+	ld.ToggleInCar(4) // not on the list, so it can not be put in the cart
+	ld.AddTemp("Kerzen", true)
+	ld.ToggleTemp(1)
+	ld.AddTemp("Servietten", true)
+	ld.AutoCheckout = &AutoCheckout{}
+	shopTime := ld.LastAddedToCar
+
+	ld.Paid()
+
+	milk := ld.ItemById(1)
+	assert.EqualValues(t, 0, milk.QuantityRequired)
+	assert.False(t, milk.IsInCar)
+	assert.Nil(t, milk.RecipeQuantities)
+	assert.EqualValues(t, 3, milk.Stock)
+	assert.EqualValues(t, []HistoryEntry{{ShopTime: shopTime, Quantity: 2}}, milk.ShopHistory)
+	assert.Len(t, milk.Batches, 1)
+	assert.EqualValues(t, 2, milk.Batches[0].Quantity)
+
+	bread := ld.ItemById(2)
+	assert.EqualValues(t, 1, bread.QuantityRequired, "not in the cart, stays on the list")
+	assert.Len(t, bread.ShopHistory, 0)
+
+	yeast := ld.ItemById(3)
+	assert.EqualValues(t, 3, yeast.QuantityRequired)
+	assert.False(t, yeast.IsNotAvailable, "may be available next time")
+
+	assert.Len(t, ld.ItemById(4).ShopHistory, 0)
+	assert.EqualValues(t, []TempItem{{Id: 2, Name: "Servietten"}}, ld.TempItems)
+	assert.EqualValues(t, []TempHistoryEntry{{Name: "Kerzen", ShopTime: shopTime}}, ld.TempHistory)
+	assert.Nil(t, ld.AutoCheckout)
+}
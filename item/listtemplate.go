@@ -0,0 +1,128 @@
+package item
+
+import (
+	"sort"
+	"strings"
+)
+
+// TemplateItem is an item of a template together with its quantity
+type TemplateItem struct {
+	ItemId   int
+	Quantity float64
+}
+
+// ListTemplate is a named set of items which can be added
+// to the list at once, e.g. everything needed for a barbecue.
+type ListTemplate struct {
+	Name  string
+	Items []TemplateItem
+	Temps []string
+}
+
+// MergeMode defines how the quantities of a template are merged into the list
+type MergeMode int
+
+const (
+	// MergeAdd adds the template quantity to the quantity required
+	MergeAdd MergeMode = iota
+	// MergeMax uses the larger of both quantities
+	MergeMax
+)
+
+func (lt *ListTemplate) Size() int {
+	return len(lt.Items) + len(lt.Temps)
+}
+
+func (ld *ListData) TemplateByName(name string) *ListTemplate {
+	for _, lt := range ld.ListTemplates {
+		if lt.Name == name {
+			return lt
+		}
+	}
+	return nil
+}
+
+// CreateTemplate creates a template from all items and temporary
+// entries currently on the list. An existing template with the same
+// name is replaced.
+func (ld *ListData) CreateTemplate(name string) *ListTemplate {
+	ld.changed()
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil
+	}
+	lt := &ListTemplate{Name: name}
+	for _, item := range ld.Items {
+		if item.QuantityRequired > 0 {
+			lt.Items = append(lt.Items, TemplateItem{ItemId: item.Id, Quantity: item.QuantityRequired})
+		}
+	}
+	for _, t := range ld.TempItems {
+		lt.Temps = append(lt.Temps, t.Name)
+	}
+
+	ld.DeleteTemplate(name)
+	ld.ListTemplates = append(ld.ListTemplates, lt)
+	sort.Slice(ld.ListTemplates, func(i, j int) bool {
+		return germanLower(ld.ListTemplates[i].Name) < germanLower(ld.ListTemplates[j].Name)
+	})
+	ld.Logger().Info("created template", "template", name)
+	return lt
+}
+
+func (ld *ListData) DeleteTemplate(name string) {
+	ld.changed()
+	for i, lt := range ld.ListTemplates {
+		if lt.Name == name {
+			ld.ListTemplates = append(ld.ListTemplates[:i], ld.ListTemplates[i+1:]...)
+			return
+		}
+	}
+}
+
+// ApplyTemplate adds the items of the template to the list.
+// Temporary entries are only added if they are not already on the list.
+func (ld *ListData) ApplyTemplate(name string, mode MergeMode) {
+	lt := ld.TemplateByName(name)
+	if lt == nil {
+		return
+	}
+	ld.auditName(AuditTemplate, 0, name, 0)
+	for _, ti := range lt.Items {
+		item := ld.ItemById(ti.ItemId)
+		if item == nil {
+			continue
+		}
+		q := ti.Quantity
+		if mode == MergeMax {
+			q = ti.Quantity - item.QuantityRequired
+		}
+		if q > 0 {
+			ld.audit(AuditModQuantity, item, q)
+			ld.modQuantity(item, q)
+		}
+	}
+	for _, t := range lt.Temps {
+		if !ld.hasTemp(t) {
+			ld.addTemp(t)
+		}
+	}
+}
+
+func (ld *ListData) hasTemp(name string) bool {
+	for _, t := range ld.TempItems {
+		if strings.EqualFold(t.Name, name) {
+			return true
+		}
+	}
+	return false
+}
+
+func (lt *ListTemplate) removeItem(itemId int) {
+	for i, ti := range lt.Items {
+		if ti.ItemId == itemId {
+			lt.Items = append(lt.Items[:i], lt.Items[i+1:]...)
+			return
+		}
+	}
+}
@@ -0,0 +1,65 @@
+package item
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestListData_Templates(t *testing.T) {
+	ld := ListData{}
+	ld.AddItem(&Item{Name: "Würstchen"})
+	ld.AddItem(&Item{Name: "Senf"})
+	ld.AddItem(&Item{Name: "Bier"})
+
+	ld.SetQuantity(1, 4)
+	ld.SetQuantity(3, 6)
+	ld.AddTemp("Grillkohle", true)
+	lt := ld.CreateTemplate(" Grillen ")
+	assert.EqualValues(t, "Grillen", lt.Name)
+	assert.EqualValues(t, 3, lt.Size())
+	assert.Nil(t, ld.CreateTemplate(" "))
+
+	ld.DeleteFromList(1)
+	ld.SetQuantity(3, 2)
+	ld.SetQuantity(2, 1)
+	ld.TempItems = nil
+
+	ld.ApplyTemplate("Grillen", MergeMax)
+	assert.EqualValues(t, 4, ld.ItemById(1).QuantityRequired)
+	assert.EqualValues(t, 1, ld.ItemById(2).QuantityRequired)
+	assert.EqualValues(t, 6, ld.ItemById(3).QuantityRequired)
+	assert.Len(t, ld.TempItems, 1)
+
+	ld.ApplyTemplate("Grillen", MergeAdd)
+	assert.EqualValues(t, 8, ld.ItemById(1).QuantityRequired)
+	assert.EqualValues(t, 12, ld.ItemById(3).QuantityRequired)
+	assert.Len(t, ld.TempItems, 1)
+
+	ld.DeleteItem(1)
+	assert.EqualValues(t, 2, lt.Size())
+	ld.ApplyTemplate("unknown", MergeAdd)
+
+	ld.DeleteTemplate("Grillen")
+	assert.Len(t, ld.ListTemplates, 0)
+}
+
+func TestListData_ApplyTemplateKeepsState(t *testing.T) {
+	for _, mode := range []MergeMode{MergeMax, MergeAdd} {
+		ld := ListData{}
+		ld.AddItem(&Item{Name: "Hack"})
+		ld.SetQuantity(1, 3)
+		ld.CreateTemplate("Grillen")
+
+		ld.SetQuantity(1, 1)
+		ld.ItemById(1).RecipeQuantities = []RecipeQuantity{{"Chili", 1}}
+		ld.SetActor("anna", "Zuhause")
+		ld.ApplyTemplate("Grillen", mode)
+
+		h := ld.ItemById(1)
+		assert.EqualValues(t, []RecipeQuantity{{"Chili", 1}}, h.RecipeQuantities)
+		last := ld.Audit[len(ld.Audit)-1]
+		assert.EqualValues(t, AuditModQuantity, last.Action)
+		assert.EqualValues(t, 1, last.ItemId)
+		assert.EqualValues(t, h.QuantityRequired-1, last.Quantity)
+	}
+}
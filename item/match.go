@@ -0,0 +1,103 @@
+package item
+
+import (
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// pluralSuffixes are removed from the words of a name to make
+// singular and plural forms comparable
+var pluralSuffixes = []string{"en", "n", "e", "s"}
+
+var unitWords = createUnitWords()
+
+func createUnitWords() map[string]struct{} {
+	m := make(map[string]struct{})
+	for s, p := range unitPluralMap {
+		m[germanLower(s)] = struct{}{}
+		m[germanLower(p)] = struct{}{}
+	}
+	return m
+}
+
+// matchKey normalizes a name for the fuzzy comparison: It is folded
+// by germanLower, unit words like "Dosen" are removed, and the plural
+// suffixes of the remaining words are stripped.
+func matchKey(name string) string {
+	words := strings.Fields(germanLower(name))
+	var key []string
+	for _, w := range words {
+		if _, isUnit := unitWords[w]; isUnit && len(words) > 1 {
+			continue
+		}
+		key = append(key, stem(w))
+	}
+	return strings.Join(key, " ")
+}
+
+func stem(w string) string {
+	for _, s := range pluralSuffixes {
+		if strings.HasSuffix(w, s) && utf8.RuneCountInString(w)-len(s) >= 3 {
+			return w[:len(w)-len(s)]
+		}
+	}
+	return w
+}
+
+// maxDistance returns the edit distance that is tolerated for a key of the given length
+func maxDistance(key string) int {
+	return utf8.RuneCountInString(key) / 5
+}
+
+// MatchItems returns the items whose name is similar to the given name.
+// The best matches come first.
+func (ld *ListData) MatchItems(name string) []*Item {
+	key := matchKey(name)
+	if key == "" {
+		return nil
+	}
+	type match struct {
+		item *Item
+		dist int
+	}
+	var matches []match
+	for _, item := range ld.Items {
+		itemKey := matchKey(item.Name)
+		d := editDistance(key, itemKey)
+		if d <= maxDistance(itemKey) {
+			matches = append(matches, match{item, d})
+		}
+	}
+	sort.SliceStable(matches, func(i, j int) bool {
+		return matches[i].dist < matches[j].dist
+	})
+	result := make([]*Item, len(matches))
+	for i, m := range matches {
+		result[i] = m.item
+	}
+	return result
+}
+
+// editDistance returns the Levenshtein distance of the two strings
+func editDistance(a, b string) int {
+	ra := []rune(a)
+	rb := []rune(b)
+	prev := make([]int, len(rb)+1)
+	cur := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		cur[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(rb)]
+}
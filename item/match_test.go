@@ -0,0 +1,69 @@
+package item
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func Test_editDistance(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"", "", 0},
+		{"milch", "milch", 0},
+		{"milch", "milc", 1},
+		{"milch", "mlich", 2},
+		{"kase", "käse", 1},
+		{"", "abc", 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.a+"-"+tt.b, func(t *testing.T) {
+			assert.EqualValues(t, tt.want, editDistance(tt.a, tt.b))
+		})
+	}
+}
+
+func TestListData_MatchItems(t *testing.T) {
+	ld := ListData{Items: []*Item{
+		{Id: 1, Name: "Milch"},
+		{Id: 2, Name: "Tomate"},
+		{Id: 3, Name: "Käse"},
+		{Id: 4, Name: "Haferflocken"},
+		{Id: 5, Name: "Mehl"},
+	}}
+	tests := []struct {
+		name string
+		want []int
+	}{
+		{"Milch", []int{1}},
+		{" milch ", []int{1}},
+		{"Tomaten", []int{2}},
+		{"Dosen Tomaten", []int{2}},
+		{"KÄSE", []int{3}},
+		{"Haferflocke", []int{4}},
+		{"Haferflokken", []int{4}},
+		{"Kerzen", nil},
+		{"Milchreis", nil},
+		{"", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []int
+			for _, i := range ld.MatchItems(tt.name) {
+				got = append(got, i.Id)
+			}
+			assert.EqualValues(t, tt.want, got)
+		})
+	}
+}
+
+func TestListData_AddTemp(t *testing.T) {
+	ld := ListData{Items: []*Item{{Id: 1, Name: "Milch"}}}
+	assert.Len(t, ld.AddTemp("Milch", false), 1)
+	assert.Len(t, ld.TempItems, 0)
+	assert.Nil(t, ld.AddTemp("Milch", true))
+	assert.Len(t, ld.TempItems, 1)
+	assert.Nil(t, ld.AddTemp("Kerzen", false))
+	assert.Len(t, ld.TempItems, 2)
+}
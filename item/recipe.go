@@ -0,0 +1,306 @@
+package item
+
+import (
+	"sort"
+	"strings"
+)
+
+// Ingredient is an item used by a recipe. The quantity
+// is given in the unit of the item.
+type Ingredient struct {
+	ItemId   int
+	Quantity float64
+}
+
+type Recipe struct {
+	Id          int
+	Name        string
+	Servings    int
+	Ingredients []Ingredient
+}
+
+// SetIngredient sets the quantity of an ingredient.
+// A quantity of zero removes the ingredient.
+func (r *Recipe) SetIngredient(itemId int, q float64) {
+	for i, in := range r.Ingredients {
+		if in.ItemId == itemId {
+			if q <= 0 {
+				r.Ingredients = append(r.Ingredients[:i], r.Ingredients[i+1:]...)
+			} else {
+				r.Ingredients[i].Quantity = q
+			}
+			return
+		}
+	}
+	if q > 0 {
+		r.Ingredients = append(r.Ingredients, Ingredient{ItemId: itemId, Quantity: q})
+	}
+}
+
+func (r *Recipe) removeItem(itemId int) {
+	r.SetIngredient(itemId, 0)
+}
+
+// PlannedMeal is a recipe planned for a day of the week
+type PlannedMeal struct {
+	Id       int
+	Day      int
+	RecipeId int
+	Servings int
+	// Added is set if the ingredients were added to the shopping list
+	Added bool `json:",omitempty"`
+}
+
+var weekDays = []string{"Montag", "Dienstag", "Mittwoch", "Donnerstag", "Freitag", "Samstag", "Sonntag"}
+
+// RecipeQuantity records the quantity of an item required by a recipe
+type RecipeQuantity struct {
+	Recipe   string
+	Quantity float64
+}
+
+func (ld *ListData) AddRecipe(name string, servings int) *Recipe {
+	ld.changed()
+	// lists written by older versions don't contain the last recipe id
+	for _, r := range ld.Recipes {
+		if r.Id > ld.LastRecipeId {
+			ld.LastRecipeId = r.Id
+		}
+	}
+	if servings < 1 {
+		servings = 1
+	}
+	ld.LastRecipeId++
+	r := &Recipe{Id: ld.LastRecipeId, Name: name, Servings: servings}
+	ld.Recipes = append(ld.Recipes, r)
+	sort.Slice(ld.Recipes, func(i, j int) bool {
+		return germanLower(ld.Recipes[i].Name) < germanLower(ld.Recipes[j].Name)
+	})
+	return r
+}
+
+// EditRecipe renames the recipe and sets the number of servings.
+// An empty name or a non-positive number of servings is ignored.
+func (ld *ListData) EditRecipe(r *Recipe, name string, servings int) {
+	ld.changed()
+	if len(name) > 0 {
+		r.Name = name
+	}
+	if servings > 0 {
+		r.Servings = servings
+	}
+}
+
+// SetIngredient sets the quantity of an ingredient of the recipe
+func (ld *ListData) SetIngredient(r *Recipe, itemId int, q float64) {
+	ld.changed()
+	r.SetIngredient(itemId, q)
+}
+
+func (ld *ListData) RecipeById(id int) *Recipe {
+	for _, r := range ld.Recipes {
+		if r.Id == id {
+			return r
+		}
+	}
+	return nil
+}
+
+func (ld *ListData) DeleteRecipe(id int) {
+	ld.changed()
+	for i, r := range ld.Recipes {
+		if r.Id == id {
+			ld.Logger().Info("deleted recipe", "recipe", r.Id, "name", r.Name)
+			ld.Recipes = append(ld.Recipes[:i], ld.Recipes[i+1:]...)
+			break
+		}
+	}
+	var plan []PlannedMeal
+	for _, m := range ld.MealPlan {
+		if m.RecipeId != id {
+			plan = append(plan, m)
+		}
+	}
+	ld.MealPlan = plan
+}
+
+// PlanMeal adds a recipe to the meal plan
+func (ld *ListData) PlanMeal(day int, recipeId int, servings int) {
+	ld.changed()
+	if day < 0 || day >= len(weekDays) {
+		return
+	}
+	r := ld.RecipeById(recipeId)
+	if r == nil {
+		return
+	}
+	if servings < 1 {
+		servings = r.Servings
+	}
+	ld.LastMealId++
+	ld.MealPlan = append(ld.MealPlan, PlannedMeal{Id: ld.LastMealId, Day: day, RecipeId: recipeId, Servings: servings})
+	sort.SliceStable(ld.MealPlan, func(i, j int) bool {
+		return ld.MealPlan[i].Day < ld.MealPlan[j].Day
+	})
+}
+
+// initMealIds assigns ids to the planned meals written by older versions
+func (ld *ListData) initMealIds() {
+	for i := range ld.MealPlan {
+		if ld.MealPlan[i].Id > ld.LastMealId {
+			ld.LastMealId = ld.MealPlan[i].Id
+		}
+	}
+	for i := range ld.MealPlan {
+		if ld.MealPlan[i].Id == 0 {
+			ld.LastMealId++
+			ld.MealPlan[i].Id = ld.LastMealId
+		}
+	}
+}
+
+// MealById returns the planned meal with the given id or nil if there is none
+func (ld *ListData) MealById(id int) *PlannedMeal {
+	for i := range ld.MealPlan {
+		if ld.MealPlan[i].Id == id {
+			return &ld.MealPlan[i]
+		}
+	}
+	return nil
+}
+
+// RemoveMeal removes the planned meal with the given id
+func (ld *ListData) RemoveMeal(id int) {
+	ld.changed()
+	for i, m := range ld.MealPlan {
+		if m.Id == id {
+			ld.MealPlan = append(ld.MealPlan[:i], ld.MealPlan[i+1:]...)
+			return
+		}
+	}
+}
+
+// NewWeek keeps the planned meals but allows to add their
+// ingredients to the shopping list again
+func (ld *ListData) NewWeek() {
+	ld.changed()
+	for i := range ld.MealPlan {
+		ld.MealPlan[i].Added = false
+	}
+	ld.Logger().Info("started new meal plan week")
+}
+
+// DayPlan describes the meals planned for a day
+type DayPlan struct {
+	Day   int
+	Name  string
+	Meals []DayMeal
+}
+
+type DayMeal struct {
+	// Id is the id of the planned meal
+	Id       int
+	Recipe   *Recipe
+	Servings int
+	Added    bool
+}
+
+// Week returns the meal plan ordered by the days of the week
+func (ld *ListData) Week() []DayPlan {
+	week := make([]DayPlan, len(weekDays))
+	for d, n := range weekDays {
+		week[d] = DayPlan{Day: d, Name: n}
+	}
+	for _, m := range ld.MealPlan {
+		if r := ld.RecipeById(m.RecipeId); r != nil && m.Day >= 0 && m.Day < len(week) {
+			week[m.Day].Meals = append(week[m.Day].Meals, DayMeal{Id: m.Id, Recipe: r, Servings: m.Servings, Added: m.Added})
+		}
+	}
+	return week
+}
+
+// MealPlanPending returns true if there are planned meals whose
+// ingredients are not yet added to the shopping list
+func (ld *ListData) MealPlanPending() bool {
+	for _, m := range ld.MealPlan {
+		if !m.Added {
+			return true
+		}
+	}
+	return false
+}
+
+// MealPlanAdded returns true if the ingredients of a planned meal
+// were added to the shopping list
+func (ld *ListData) MealPlanAdded() bool {
+	for _, m := range ld.MealPlan {
+		if m.Added {
+			return true
+		}
+	}
+	return false
+}
+
+// AddMealPlan adds the ingredients of all planned meals to the shopping list.
+// The quantities are scaled by the planned servings and summed up per item.
+// The sum is rounded up to the increment of the item's unit. Meals whose
+// ingredients were already added are skipped.
+func (ld *ListData) AddMealPlan() {
+	type required struct {
+		item    *Item
+		total   float64
+		sources []RecipeQuantity
+	}
+	var order []*required
+	m := make(map[int]*required)
+	for i := range ld.MealPlan {
+		meal := &ld.MealPlan[i]
+		if meal.Added {
+			continue
+		}
+		meal.Added = true
+		ld.changed()
+		r := ld.RecipeById(meal.RecipeId)
+		if r == nil {
+			continue
+		}
+		f := float64(meal.Servings) / float64(max(r.Servings, 1))
+		for _, in := range r.Ingredients {
+			item := ld.ItemById(in.ItemId)
+			if item == nil {
+				continue
+			}
+			req, ok := m[in.ItemId]
+			if !ok {
+				req = &required{item: item}
+				m[in.ItemId] = req
+				order = append(order, req)
+			}
+			q := in.Quantity * f
+			req.total += q
+			req.sources = append(req.sources, RecipeQuantity{Recipe: r.Name, Quantity: q})
+		}
+	}
+
+	if len(order) > 0 {
+		ld.audit(AuditMealPlan, nil, 0)
+	}
+	for _, req := range order {
+		ld.modQuantity(req.item, roundUp(req.total, req.item.Increment()))
+		req.item.RecipeQuantities = append(req.item.RecipeQuantities, req.sources...)
+	}
+}
+
+const eps = 1e-6
+
+// RecipesStr describes the recipes the required quantity comes from
+func (i *Item) RecipesStr() string {
+	var sb strings.Builder
+	for _, rq := range i.RecipeQuantities {
+		if sb.Len() > 0 {
+			sb.WriteString(", ")
+		}
+		sb.WriteString(rq.Recipe)
+	}
+	return sb.String()
+}
@@ -0,0 +1,128 @@
+package item
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+)
+
+func TestListData_AddMealPlan(t *testing.T) {
+	ld := ListData{}
+	ld.AddItem(New("Nudeln", "Packung", 500, "", 0, "", "", nil))
+	ld.AddItem(New("Hack", "g", 0, "", 0, "", "", nil))
+	ld.AddItem(New("Tomaten", "Dose", 0, "", 0, "", "", nil))
+
+	bolo := ld.AddRecipe("Bolognese", 4)
+	bolo.SetIngredient(1, 1)
+	bolo.SetIngredient(2, 400)
+	bolo.SetIngredient(3, 1)
+	chili := ld.AddRecipe("Chili", 2)
+	chili.SetIngredient(2, 250)
+	chili.SetIngredient(3, 1)
+
+	ld.PlanMeal(0, bolo.Id, 2)
+	ld.PlanMeal(3, chili.Id, 0)
+	ld.PlanMeal(7, chili.Id, 2)
+	assert.Len(t, ld.MealPlan, 2)
+
+	assert.True(t, ld.MealPlanPending())
+	ld.AddMealPlan()
+
+	assert.EqualValues(t, 1, ld.ItemById(1).QuantityRequired)
+	assert.EqualValues(t, 450, ld.ItemById(2).QuantityRequired)
+	assert.EqualValues(t, 2, ld.ItemById(3).QuantityRequired)
+	assert.EqualValues(t, []RecipeQuantity{{"Bolognese", 200}, {"Chili", 250}}, ld.ItemById(2).RecipeQuantities)
+	assert.False(t, ld.MealPlanPending())
+
+	// adding the plan again does not change the list
+	ld.AddMealPlan()
+	assert.EqualValues(t, 1, ld.ItemById(1).QuantityRequired)
+	assert.EqualValues(t, 450, ld.ItemById(2).QuantityRequired)
+	assert.EqualValues(t, 2, ld.ItemById(3).QuantityRequired)
+
+	// only the meal planned later is added
+	ld.PlanMeal(5, chili.Id, 2)
+	assert.True(t, ld.MealPlanPending())
+	ld.AddMealPlan()
+	assert.EqualValues(t, 1, ld.ItemById(1).QuantityRequired)
+	assert.EqualValues(t, 700, ld.ItemById(2).QuantityRequired)
+	assert.EqualValues(t, 3, ld.ItemById(3).QuantityRequired)
+
+	ld.DeleteItem(3)
+	assert.Len(t, bolo.Ingredients, 2)
+	ld.DeleteRecipe(chili.Id)
+	assert.Len(t, ld.MealPlan, 1)
+}
+
+func TestListData_NewWeek(t *testing.T) {
+	ld := ListData{}
+	ld.AddItem(New("Hack", "g", 0, "", 0, "", "", nil))
+	chili := ld.AddRecipe("Chili", 2)
+	chili.SetIngredient(1, 250)
+	ld.PlanMeal(3, chili.Id, 2)
+
+	ld.AddMealPlan()
+	assert.EqualValues(t, 250, ld.ItemById(1).QuantityRequired)
+	assert.True(t, ld.MealPlanAdded())
+
+	ld.NewWeek()
+	assert.Len(t, ld.MealPlan, 1)
+	assert.False(t, ld.MealPlanAdded())
+	assert.True(t, ld.MealPlanPending())
+	ld.AddMealPlan()
+	assert.EqualValues(t, 500, ld.ItemById(1).QuantityRequired)
+}
+
+func TestListData_StableMealIds(t *testing.T) {
+	ld := ListData{}
+	soup := ld.AddRecipe("Suppe", 2)
+	ld.PlanMeal(2, soup.Id, 2)
+	ld.PlanMeal(0, soup.Id, 2)
+	ld.PlanMeal(4, soup.Id, 2)
+	assert.EqualValues(t, []int{2, 1, 3}, mealIds(ld.MealPlan))
+
+	ld.RemoveMeal(2)
+	ld.RemoveMeal(2)
+	assert.EqualValues(t, []int{1, 3}, mealIds(ld.MealPlan), "removing twice removes a single meal")
+	assert.Nil(t, ld.MealById(2))
+
+	ld.PlanMeal(6, soup.Id, 2)
+	assert.EqualValues(t, []int{1, 3, 4}, mealIds(ld.MealPlan), "the id of a removed meal is not reused")
+
+	// meals written by older versions get ids when loaded
+	loaded, err := Load(strings.NewReader(`{"MealPlan":[{"Day":1,"RecipeId":1,"Servings":2},{"Day":3,"RecipeId":1,"Servings":2}]}`))
+	assert.NoError(t, err)
+	assert.EqualValues(t, []int{1, 2}, mealIds(loaded.MealPlan))
+}
+
+func mealIds(plan []PlannedMeal) []int {
+	var ids []int
+	for _, m := range plan {
+		ids = append(ids, m.Id)
+	}
+	return ids
+}
+
+func TestListData_StableRecipeIds(t *testing.T) {
+	ld := ListData{}
+	soup := ld.AddRecipe("Suppe", 2)
+	cake := ld.AddRecipe("Kuchen", 8)
+	assert.EqualValues(t, 1, soup.Id)
+	assert.EqualValues(t, 2, cake.Id)
+
+	ld.DeleteRecipe(cake.Id)
+	pizza := ld.AddRecipe("Pizza", 2)
+	assert.EqualValues(t, 3, pizza.Id, "the id of a deleted recipe is not reused")
+	assert.Nil(t, ld.RecipeById(2))
+
+	var buf bytes.Buffer
+	assert.NoError(t, ld.Save(&buf))
+	loaded, err := Load(&buf)
+	assert.NoError(t, err)
+	assert.EqualValues(t, 4, loaded.AddRecipe("Salat", 1).Id)
+
+	// lists written by older versions don't contain the last recipe id
+	old := ListData{Recipes: []*Recipe{{Id: 5, Name: "Alt", Servings: 1}}}
+	assert.EqualValues(t, 6, old.AddRecipe("Neu", 1).Id)
+}
@@ -0,0 +1,119 @@
+package item
+
+import (
+	"time"
+)
+
+// RecurrenceUnit is the unit of the interval of a recurrence
+type RecurrenceUnit string
+
+const (
+	Days   RecurrenceUnit = "d"
+	Weeks  RecurrenceUnit = "w"
+	Months RecurrenceUnit = "m"
+)
+
+// Recurrence describes an item which is bought on a fixed schedule
+// regardless of its shopping history, e.g. cat litter every month.
+type Recurrence struct {
+	Every    int
+	Unit     RecurrenceUnit
+	Quantity float64
+	NextDue  time.Time
+	// Day is the day of the month a monthly schedule is anchored to.
+	// In shorter months the last day of the month is used.
+	Day int `json:",omitempty"`
+}
+
+// NewRecurrence creates a new recurrence. Returns nil if the interval is not valid.
+func NewRecurrence(every int, unit RecurrenceUnit, quantity float64) *Recurrence {
+	if every <= 0 {
+		return nil
+	}
+	switch unit {
+	case Days, Weeks, Months:
+	default:
+		return nil
+	}
+	if quantity <= 0 {
+		quantity = 1
+	}
+	return &Recurrence{Every: every, Unit: unit, Quantity: quantity}
+}
+
+func (r *Recurrence) sameInterval(o *Recurrence) bool {
+	return r != nil && o != nil && r.Every == o.Every && r.Unit == o.Unit
+}
+
+// next returns the due date following the given one
+func (r *Recurrence) next(t time.Time) time.Time {
+	switch r.Unit {
+	case Weeks:
+		return t.AddDate(0, 0, 7*r.Every)
+	case Months:
+		day := r.Day
+		if day == 0 {
+			day = t.Day()
+		}
+		y, m, _ := t.Date()
+		m += time.Month(r.Every)
+		// day zero of the following month is the last day of the month
+		if last := time.Date(y, m+1, 0, 0, 0, 0, 0, t.Location()).Day(); day > last {
+			day = last
+		}
+		return time.Date(y, m, day, t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), t.Location())
+	default:
+		return t.AddDate(0, 0, r.Every)
+	}
+}
+
+// start sets the first due date of a new recurrence.
+// If the item was bought before, the schedule starts at the last purchase.
+func (r *Recurrence) start(history []HistoryEntry) {
+	start := toDay(time.Now())
+	if len(history) > 0 {
+		start = toDay(history[len(history)-1].ShopTime)
+	}
+	if r.Unit == Months {
+		r.Day = start.Day()
+	}
+	r.NextDue = r.next(start)
+}
+
+// NextDue returns the date the item is put on the list next
+// or the zero time if the item has no recurrence.
+func (i *Item) NextDue() time.Time {
+	if i.Recurrence == nil {
+		return time.Time{}
+	}
+	return i.Recurrence.NextDue
+}
+
+// checkRecurrences puts all items on the list which are due
+func (ld *ListData) checkRecurrences() {
+	now := time.Now()
+	for _, item := range ld.Items {
+		ld.checkRecurrence(item, now)
+	}
+}
+
+func (ld *ListData) checkRecurrence(i *Item, now time.Time) {
+	r := i.Recurrence
+	if r == nil || r.NextDue.After(now) {
+		return
+	}
+	ld.Logger().Debug("recurrence due", "item", i.Id, "name", i.Name)
+	if i.QuantityRequired < r.Quantity {
+		i.QuantityRequired = r.Quantity
+		i.IsNotAvailable = false
+		ld.audit(AuditSetQuantity, i, r.Quantity)
+	}
+	// schedules written by older versions are anchored to the current due date
+	if r.Unit == Months && r.Day == 0 {
+		r.Day = r.NextDue.Day()
+	}
+	ld.changed()
+	for !r.NextDue.After(now) {
+		r.NextDue = r.next(r.NextDue)
+	}
+}
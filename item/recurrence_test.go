@@ -0,0 +1,89 @@
+package item
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestNewRecurrence(t *testing.T) {
+	assert.Nil(t, NewRecurrence(0, Days, 1))
+	assert.Nil(t, NewRecurrence(2, "x", 1))
+	assert.EqualValues(t, 1, NewRecurrence(2, Weeks, 0).Quantity)
+}
+
+func TestListData_checkRecurrence(t *testing.T) {
+	now := time.Date(2025, 3, 10, 12, 0, 0, 0, time.Local)
+	r := NewRecurrence(2, Weeks, 1)
+	r.NextDue = time.Date(2025, 2, 20, 0, 0, 0, 0, time.Local)
+	ld := ListData{}
+	ld.AddItem(&Item{Name: "Kaffee", Recurrence: r, IsInCar: true})
+	i := ld.ItemById(1)
+
+	ld.checkRecurrence(i, now)
+	assert.EqualValues(t, 1, i.QuantityRequired)
+	assert.True(t, i.IsInCar, "the cart is left alone")
+	assert.EqualValues(t, time.Date(2025, 3, 20, 0, 0, 0, 0, time.Local), r.NextDue)
+	last := ld.Audit[len(ld.Audit)-1]
+	assert.EqualValues(t, AuditSetQuantity, last.Action)
+	assert.EqualValues(t, 1, last.ItemId)
+	assert.EqualValues(t, 1, last.Quantity)
+
+	i.QuantityRequired = 0
+	ld.checkRecurrence(i, now)
+	assert.EqualValues(t, 0, i.QuantityRequired)
+
+	m := &Item{Name: "Katzenstreu", Recurrence: NewRecurrence(1, Months, 2), QuantityRequired: 3}
+	ld.AddItem(m)
+	m.Recurrence.NextDue = time.Date(2025, 3, 10, 0, 0, 0, 0, time.Local)
+	n := len(ld.Audit)
+	ld.checkRecurrence(m, now)
+	assert.EqualValues(t, 3, m.QuantityRequired)
+	assert.Len(t, ld.Audit, n, "the quantity is not changed")
+	assert.EqualValues(t, time.Date(2025, 4, 10, 0, 0, 0, 0, time.Local), m.Recurrence.NextDue)
+}
+
+func TestRecurrence_nextMonth(t *testing.T) {
+	r := NewRecurrence(1, Months, 1)
+	r.start([]HistoryEntry{{ShopTime: time.Date(2025, 1, 31, 18, 0, 0, 0, time.Local), Quantity: 1}})
+	var due []time.Time
+	for range 5 {
+		due = append(due, r.NextDue)
+		r.NextDue = r.next(r.NextDue)
+	}
+	assert.EqualValues(t, []time.Time{
+		time.Date(2025, 2, 28, 0, 0, 0, 0, time.Local),
+		time.Date(2025, 3, 31, 0, 0, 0, 0, time.Local),
+		time.Date(2025, 4, 30, 0, 0, 0, 0, time.Local),
+		time.Date(2025, 5, 31, 0, 0, 0, 0, time.Local),
+		time.Date(2025, 6, 30, 0, 0, 0, 0, time.Local),
+	}, due)
+
+	q := NewRecurrence(3, Months, 1)
+	q.Day = 30
+	assert.EqualValues(t, time.Date(2024, 2, 29, 0, 0, 0, 0, time.Local), q.next(time.Date(2023, 11, 30, 0, 0, 0, 0, time.Local)))
+	assert.EqualValues(t, time.Date(2025, 1, 30, 0, 0, 0, 0, time.Local), q.next(time.Date(2024, 10, 30, 0, 0, 0, 0, time.Local)))
+
+	// schedules written by older versions are anchored when they are due
+	ld := ListData{}
+	old := &Recurrence{Every: 1, Unit: Months, Quantity: 1, NextDue: time.Date(2025, 1, 31, 0, 0, 0, 0, time.Local)}
+	ld.AddItem(&Item{Name: "Katzenstreu", Recurrence: old})
+	ld.checkRecurrence(ld.ItemById(1), time.Date(2025, 3, 5, 0, 0, 0, 0, time.Local))
+	assert.EqualValues(t, time.Date(2025, 3, 31, 0, 0, 0, 0, time.Local), old.NextDue)
+}
+
+func TestListData_ReplaceRecurrence(t *testing.T) {
+	ld := ListData{}
+	ld.AddItem(&Item{Name: "Katzenstreu"})
+	ld.Items[0].ShopHistory = []HistoryEntry{{time.Now().AddDate(0, 0, -3), 1}}
+
+	ld.Replace(1, &Item{Name: "Katzenstreu", Recurrence: NewRecurrence(1, Weeks, 1)})
+	due := ld.ItemById(1).NextDue()
+	assert.EqualValues(t, toDay(time.Now().AddDate(0, 0, 4)), due)
+
+	ld.Replace(1, &Item{Name: "Katzenstreu", Recurrence: NewRecurrence(1, Weeks, 2)})
+	assert.EqualValues(t, due, ld.ItemById(1).NextDue())
+
+	ld.Replace(1, &Item{Name: "Katzenstreu"})
+	assert.True(t, ld.ItemById(1).NextDue().IsZero())
+}
@@ -0,0 +1,95 @@
+package item
+
+import (
+	"log/slog"
+	"math"
+)
+
+// Consume reduces the stock of the item by the given quantity.
+// If the stock falls below the minimum stock, the item is put on the list.
+func (ld *ListData) Consume(id int, q float64) {
+	ld.changed()
+	if item := ld.ItemById(id); item != nil && item.TrackStock {
+		ld.Logger().Info("consumed", "item", item.Id, "name", item.Name, "quantity", q)
+		item.Stock -= q
+		if item.Stock < 0.001 {
+			item.Stock = 0
+		}
+		item.consumeBatches(q)
+		item.suggestedQuantityCalculated = false
+		item.checkMinStock()
+	}
+}
+
+// SetStock sets the stock of the item, e.g. after counting the pantry
+func (ld *ListData) SetStock(id int, q float64) {
+	ld.changed()
+	if item := ld.ItemById(id); item != nil && item.TrackStock {
+		if q < 0 {
+			q = 0
+		}
+		item.Stock = q
+		item.trimBatches(q)
+		item.suggestedQuantityCalculated = false
+		item.checkMinStock()
+	}
+}
+
+// StockItems returns the items whose stock is tracked
+func (ld *ListData) StockItems() []*Item {
+	var items []*Item
+	for _, item := range ld.Items {
+		if item.TrackStock {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+func (ld *ListData) checkMinStock() {
+	for _, item := range ld.Items {
+		item.checkMinStock()
+	}
+}
+
+// checkMinStock puts the item on the list if the stock is below the minimum stock
+func (i *Item) checkMinStock() {
+	if !i.TrackStock || i.Stock >= i.MinStock {
+		return
+	}
+	need := roundUp(i.MinStock-i.Stock, i.Increment())
+	if i.QuantityRequired < need {
+		slog.Debug("below min stock", "item", i.Id, "name", i.Name, "stock", i.Stock)
+		i.QuantityRequired = need
+		i.IsInCar = false
+		i.IsNotAvailable = false
+	}
+}
+
+// IsBelowMinStock returns true if the stock is below the minimum stock
+func (i *Item) IsBelowMinStock() bool {
+	return i.TrackStock && i.Stock < i.MinStock
+}
+
+// stockSuggestion returns the quantity required to keep the stock above
+// the minimum stock until the next shopping trip. If the shopping history
+// allows to estimate the consumption, it is taken into account.
+func (i *Item) stockSuggestion() float64 {
+	need := i.MinStock - i.Stock
+	c, ok := i.consumption()
+	if ok && c.hoursPerItem > 0 {
+		need += 24 * daysShoppingHasToLast / c.hoursPerItem
+	}
+	if need <= 0 {
+		return 0
+	}
+	need = roundUp(need, i.Increment())
+	if ok {
+		return c.limit(need)
+	}
+	return need
+}
+
+func roundUp(q, inc float64) float64 {
+	return math.Ceil(q/inc-eps) * inc
+}
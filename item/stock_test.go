@@ -0,0 +1,67 @@
+package item
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestListData_Stock(t *testing.T) {
+	ld := ListData{}
+	ld.AddItem(&Item{Name: "Kaffee", UnitDef: "Packung", TrackStock: true, MinStock: 1})
+	ld.AddItem(&Item{Name: "Milch", UnitDef: "Packung"})
+
+	ld.SetQuantity(1, 3)
+	ld.SetQuantity(2, 2)
+	ld.ToggleInCar(1)
+	ld.ToggleInCar(2)
+	ld.Paid()
+
+	kaffee := ld.ItemById(1)
+	assert.EqualValues(t, 3, kaffee.Stock)
+	assert.EqualValues(t, 0, ld.ItemById(2).Stock)
+
+	ld.Consume(1, 1)
+	assert.EqualValues(t, 2, kaffee.Stock)
+	assert.EqualValues(t, 0, kaffee.QuantityRequired)
+
+	ld.Consume(1, 1.5)
+	assert.EqualValues(t, 0.5, kaffee.Stock)
+	assert.True(t, kaffee.IsBelowMinStock())
+	assert.EqualValues(t, 1, kaffee.QuantityRequired)
+
+	ld.Consume(1, 5)
+	assert.EqualValues(t, 0, kaffee.Stock)
+
+	ld.Consume(2, 1)
+	assert.EqualValues(t, 0, ld.ItemById(2).Stock)
+}
+
+func TestItem_stockSuggestion(t *testing.T) {
+	n := time.Now()
+	day := 24 * time.Hour
+	tests := []struct {
+		name string
+		item Item
+		want float64
+	}{
+		{"no history, enough", Item{TrackStock: true, Stock: 2, MinStock: 1}, 0},
+		{"no history, below", Item{TrackStock: true, Stock: 0.5, MinStock: 2}, 2},
+		{"no history, grams", Item{TrackStock: true, UnitDef: "g", Stock: 120, MinStock: 500}, 400},
+		{"one per two days", Item{TrackStock: true, Stock: 1, MinStock: 1, ShopHistory: []HistoryEntry{
+			{n.Add(-12 * day), 2},
+			{n.Add(-8 * day), 2},
+			{n.Add(-4 * day), 2},
+		}}, 2},
+		{"stock lasts", Item{TrackStock: true, Stock: 5, MinStock: 1, ShopHistory: []HistoryEntry{
+			{n.Add(-12 * day), 2},
+			{n.Add(-8 * day), 2},
+			{n.Add(-4 * day), 2},
+		}}, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.EqualValues(t, tt.want, tt.item.Suggest())
+		})
+	}
+}
@@ -0,0 +1,93 @@
+package listen
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+)
+
+// ParseNetworks parses a comma separated list of networks in CIDR
+// notation. Single addresses are also allowed.
+func ParseNetworks(list string) ([]*net.IPNet, error) {
+	var nets []*net.IPNet
+	for _, s := range strings.Split(list, ",") {
+		s = strings.TrimSpace(s)
+		if s == "" {
+			continue
+		}
+		if !strings.Contains(s, "/") {
+			ip := net.ParseIP(s)
+			if ip == nil {
+				return nil, fmt.Errorf("invalid address %s", s)
+			}
+			bits := 128
+			if ip.To4() != nil {
+				bits = 32
+			}
+			s = fmt.Sprintf("%s/%d", s, bits)
+		}
+		_, n, err := net.ParseCIDR(s)
+		if err != nil {
+			return nil, err
+		}
+		nets = append(nets, n)
+	}
+	return nets, nil
+}
+
+func contains(nets []*net.IPNet, ip net.IP) bool {
+	for _, n := range nets {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+func remoteIP(remoteAddr string) net.IP {
+	host, _, err := net.SplitHostPort(remoteAddr)
+	if err != nil {
+		host = remoteAddr
+	}
+	return net.ParseIP(host)
+}
+
+// Forwarded evaluates the X-Forwarded-For, X-Forwarded-Proto and X-Forwarded-Host
+// headers if the request comes from a trusted proxy. The client address is the
+// last address in X-Forwarded-For which is not a trusted proxy itself.
+// If the request does not come from a trusted proxy, the headers are removed,
+// so that a client can not fake them.
+func Forwarded(trusted []*net.IPNet, parent http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ip := remoteIP(r.RemoteAddr)
+		if ip == nil || !contains(trusted, ip) {
+			r.Header.Del("X-Forwarded-For")
+			r.Header.Del("X-Forwarded-Proto")
+			r.Header.Del("X-Forwarded-Host")
+			parent.ServeHTTP(w, r)
+			return
+		}
+
+		if fwd := r.Header.Values("X-Forwarded-For"); len(fwd) > 0 {
+			addrs := strings.Split(strings.Join(fwd, ","), ",")
+			for i := len(addrs) - 1; i >= 0; i-- {
+				client := net.ParseIP(strings.TrimSpace(addrs[i]))
+				if client == nil {
+					break
+				}
+				r.RemoteAddr = net.JoinHostPort(client.String(), "0")
+				if !contains(trusted, client) {
+					break
+				}
+			}
+		}
+		if proto := r.Header.Get("X-Forwarded-Proto"); proto == "http" || proto == "https" {
+			r.URL.Scheme = proto
+		}
+		if host := r.Header.Get("X-Forwarded-Host"); host != "" {
+			r.Host = host
+		}
+		parent.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,73 @@
+package listen
+
+import (
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestParseNetworks(t *testing.T) {
+	nets, err := ParseNetworks("127.0.0.1, ::1,10.0.0.0/8,")
+	assert.NoError(t, err)
+	assert.Len(t, nets, 3)
+	assert.EqualValues(t, "127.0.0.1/32", nets[0].String())
+	assert.EqualValues(t, "::1/128", nets[1].String())
+
+	_, err = ParseNetworks("localhost")
+	assert.Error(t, err)
+	_, err = ParseNetworks("10.0.0.0/33")
+	assert.Error(t, err)
+}
+
+func TestForwarded(t *testing.T) {
+	trusted, err := ParseNetworks("127.0.0.1,10.0.0.0/8")
+	assert.NoError(t, err)
+
+	tests := []struct {
+		name       string
+		remoteAddr string
+		forwarded  string
+		proto      string
+		host       string
+		wantAddr   string
+		wantScheme string
+		wantHost   string
+		wantHeader bool
+	}{
+		{"trusted", "127.0.0.1:4711", "192.168.1.5", "https", "shop.example.org", "192.168.1.5:0", "https", "shop.example.org", true},
+		{"chain", "127.0.0.1:4711", "1.2.3.4, 192.168.1.5, 10.0.0.2", "", "", "192.168.1.5:0", "", "example.com", true},
+		{"spoofed", "192.168.1.7:4711", "1.2.3.4", "https", "evil.org", "192.168.1.7:4711", "", "example.com", false},
+		{"invalid", "127.0.0.1:4711", "unknown", "ftp", "", "127.0.0.1:4711", "", "example.com", true},
+		{"no header", "127.0.0.1:4711", "", "", "", "127.0.0.1:4711", "", "example.com", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got *http.Request
+			h := Forwarded(trusted, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				got = r
+			}))
+			r := httptest.NewRequest(http.MethodGet, "http://example.com/", nil)
+			r.RemoteAddr = tt.remoteAddr
+			if tt.forwarded != "" {
+				r.Header.Set("X-Forwarded-For", tt.forwarded)
+			}
+			if tt.proto != "" {
+				r.Header.Set("X-Forwarded-Proto", tt.proto)
+			}
+			if tt.host != "" {
+				r.Header.Set("X-Forwarded-Host", tt.host)
+			}
+			h.ServeHTTP(httptest.NewRecorder(), r)
+
+			assert.EqualValues(t, tt.wantAddr, got.RemoteAddr)
+			if tt.wantScheme != "" {
+				assert.EqualValues(t, tt.wantScheme, got.URL.Scheme)
+			}
+			assert.EqualValues(t, tt.wantHost, got.Host)
+			if !tt.wantHeader {
+				assert.Empty(t, got.Header.Get("X-Forwarded-For"))
+			}
+		})
+	}
+}
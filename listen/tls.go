@@ -0,0 +1,240 @@
+// Package listen contains the helpers needed to run the server either with
+// TLS or as plain HTTP server behind a reverse proxy.
+package listen
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"io/fs"
+	"log/slog"
+	"math/big"
+	"net"
+	"os"
+	"sync"
+	"time"
+)
+
+const (
+	checkInterval = 10 * time.Second
+	// selfSignedValidity is the validity of the self-signed certificates.
+	// Apple devices reject server certificates valid for more than 825 days,
+	// browsers accept at most 398 days.
+	selfSignedValidity = 397 * 24 * time.Hour
+	maxValidity        = 825 * 24 * time.Hour
+	// renewBefore is the time before the expiry a self-signed certificate is replaced
+	renewBefore = 30 * 24 * time.Hour
+)
+
+// CertReloader provides the certificate to the TLS server.
+// If the certificate files are modified, the certificate is reloaded,
+// so that a renewed certificate is used without a restart.
+type CertReloader struct {
+	certFile   string
+	keyFile    string
+	selfSigned bool
+
+	mutex     sync.Mutex
+	cert      *tls.Certificate
+	modTime   time.Time
+	lastCheck time.Time
+	interval  time.Duration
+}
+
+// NewCertReloader creates a new reloader. The certificate is loaded
+// immediately, so a missing or invalid certificate is reported at startup.
+func NewCertReloader(certFile, keyFile string) (*CertReloader, error) {
+	cr := &CertReloader{certFile: certFile, keyFile: keyFile, interval: checkInterval}
+	err := cr.load()
+	if err != nil {
+		return nil, err
+	}
+	return cr, nil
+}
+
+// NewSelfSignedReloader creates a reloader using a self-signed certificate.
+// The certificate is created if it is missing and renewed before it expires.
+func NewSelfSignedReloader(certFile, keyFile string) (*CertReloader, error) {
+	_, err := EnsureSelfSigned(certFile, keyFile)
+	if err != nil {
+		return nil, err
+	}
+	cr, err := NewCertReloader(certFile, keyFile)
+	if err != nil {
+		return nil, err
+	}
+	cr.selfSigned = true
+	return cr, nil
+}
+
+func (cr *CertReloader) modified() (time.Time, error) {
+	var latest time.Time
+	for _, f := range []string{cr.certFile, cr.keyFile} {
+		s, err := os.Stat(f)
+		if err != nil {
+			return time.Time{}, err
+		}
+		if s.ModTime().After(latest) {
+			latest = s.ModTime()
+		}
+	}
+	return latest, nil
+}
+
+func (cr *CertReloader) load() error {
+	modTime, err := cr.modified()
+	if err != nil {
+		return err
+	}
+	cert, err := tls.LoadX509KeyPair(cr.certFile, cr.keyFile)
+	if err != nil {
+		return err
+	}
+	cr.cert = &cert
+	cr.modTime = modTime
+	return nil
+}
+
+// GetCertificate is used as tls.Config.GetCertificate
+func (cr *CertReloader) GetCertificate(_ *tls.ClientHelloInfo) (*tls.Certificate, error) {
+	cr.mutex.Lock()
+	defer cr.mutex.Unlock()
+
+	now := time.Now()
+	if now.Sub(cr.lastCheck) >= cr.interval {
+		cr.lastCheck = now
+		if cr.selfSigned {
+			if _, err := ensureSelfSigned(cr.certFile, cr.keyFile, now); err != nil {
+				slog.Error("could not renew certificate", "cert", cr.certFile, "err", err)
+			}
+		}
+		modTime, err := cr.modified()
+		if err != nil {
+			slog.Error("could not check certificate", "cert", cr.certFile, "err", err)
+		} else if !modTime.Equal(cr.modTime) {
+			err = cr.load()
+			if err != nil {
+				// keep the old certificate, maybe the files are written right now
+				slog.Error("could not reload certificate", "cert", cr.certFile, "err", err)
+			} else {
+				slog.Info("reloaded certificate", "cert", cr.certFile)
+			}
+		}
+	}
+	return cr.cert, nil
+}
+
+// TLSConfig returns a TLS configuration using the reloader
+func (cr *CertReloader) TLSConfig() *tls.Config {
+	return &tls.Config{
+		MinVersion:     tls.VersionTLS12,
+		GetCertificate: cr.GetCertificate,
+	}
+}
+
+// EnsureSelfSigned creates a self-signed certificate if the certificate file
+// does not exist, if the certificate expires soon or if it is valid for too
+// long to be accepted by Apple devices. This is useful to run the server in
+// a local network. Returns true if a certificate was created.
+func EnsureSelfSigned(certFile, keyFile string) (bool, error) {
+	return ensureSelfSigned(certFile, keyFile, time.Now())
+}
+
+func ensureSelfSigned(certFile, keyFile string, now time.Time) (bool, error) {
+	data, err := os.ReadFile(certFile)
+	if err == nil {
+		renew, err := renewalDue(data, now)
+		if err != nil || !renew {
+			return false, err
+		}
+	} else if !errors.Is(err, fs.ErrNotExist) {
+		return false, err
+	}
+
+	certPEM, keyPEM, err := selfSigned(now, selfSignedValidity)
+	if err != nil {
+		return false, err
+	}
+	err = os.WriteFile(keyFile, keyPEM, 0600)
+	if err != nil {
+		return false, err
+	}
+	err = os.WriteFile(certFile, certPEM, 0644)
+	if err != nil {
+		return false, err
+	}
+	slog.Info("created self-signed certificate", "cert", certFile)
+	return true, nil
+}
+
+// renewalDue returns true if the certificate expires within renewBefore or
+// if it is valid for longer than maxValidity
+func renewalDue(certPEM []byte, now time.Time) (bool, error) {
+	block, _ := pem.Decode(certPEM)
+	if block == nil || block.Type != "CERTIFICATE" {
+		return false, errors.New("no certificate found")
+	}
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return false, err
+	}
+	return now.Add(renewBefore).After(cert.NotAfter) || cert.NotAfter.Sub(cert.NotBefore) > maxValidity, nil
+}
+
+func selfSigned(now time.Time, validity time.Duration) ([]byte, []byte, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, nil, err
+	}
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	hosts := []string{"localhost"}
+	if h, err := os.Hostname(); err == nil && h != "localhost" {
+		hosts = append(hosts, h)
+	}
+	template := x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               pkix.Name{Organization: []string{"Shopping"}, CommonName: hosts[len(hosts)-1]},
+		NotBefore:             now.Add(-time.Hour),
+		NotAfter:              now.Add(validity),
+		KeyUsage:              x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+		DNSNames:              hosts,
+		IPAddresses:           localIPs(),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, &template, &template, &key.PublicKey, key)
+	if err != nil {
+		return nil, nil, err
+	}
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return nil, nil, err
+	}
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer})
+	return certPEM, keyPEM, nil
+}
+
+// localIPs returns the addresses of all local network interfaces
+func localIPs() []net.IP {
+	ips := []net.IP{net.IPv4(127, 0, 0, 1), net.IPv6loopback}
+	addrs, err := net.InterfaceAddrs()
+	if err != nil {
+		return ips
+	}
+	for _, a := range addrs {
+		if n, ok := a.(*net.IPNet); ok && !n.IP.IsLoopback() {
+			ips = append(ips, n.IP)
+		}
+	}
+	return ips
+}
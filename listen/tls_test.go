@@ -0,0 +1,144 @@
+package listen
+
+import (
+	"crypto/x509"
+	"encoding/pem"
+	"github.com/stretchr/testify/assert"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestSelfSignedAndReload(t *testing.T) {
+	dir := t.TempDir()
+	certFile := filepath.Join(dir, "cert.pem")
+	keyFile := filepath.Join(dir, "cert.key")
+
+	created, err := EnsureSelfSigned(certFile, keyFile)
+	assert.NoError(t, err)
+	assert.True(t, created)
+	created, err = EnsureSelfSigned(certFile, keyFile)
+	assert.NoError(t, err)
+	assert.False(t, created)
+
+	s, err := os.Stat(keyFile)
+	assert.NoError(t, err)
+	assert.EqualValues(t, 0600, s.Mode().Perm())
+
+	cr, err := NewCertReloader(certFile, keyFile)
+	assert.NoError(t, err)
+	cr.interval = 0
+	first, err := cr.GetCertificate(nil)
+	assert.NoError(t, err)
+	leaf, err := x509.ParseCertificate(first.Certificate[0])
+	assert.NoError(t, err)
+	assert.Contains(t, leaf.DNSNames, "localhost")
+
+	same, err := cr.GetCertificate(nil)
+	assert.NoError(t, err)
+	assert.True(t, first == same)
+
+	// replace the certificate
+	certPEM, keyPEM, err := selfSigned(time.Now(), selfSignedValidity)
+	assert.NoError(t, err)
+	assert.NoError(t, os.WriteFile(keyFile, keyPEM, 0600))
+	assert.NoError(t, os.WriteFile(certFile, certPEM, 0644))
+	later := time.Now().Add(time.Minute)
+	assert.NoError(t, os.Chtimes(certFile, later, later))
+
+	second, err := cr.GetCertificate(nil)
+	assert.NoError(t, err)
+	assert.False(t, first == second)
+
+	// broken files keep the old certificate
+	assert.NoError(t, os.WriteFile(certFile, []byte("broken"), 0644))
+	later = later.Add(time.Minute)
+	assert.NoError(t, os.Chtimes(certFile, later, later))
+	third, err := cr.GetCertificate(nil)
+	assert.NoError(t, err)
+	assert.True(t, second == third)
+}
+
+func TestEnsureSelfSigned_Renew(t *testing.T) {
+	dir := t.TempDir()
+	certFile := filepath.Join(dir, "cert.pem")
+	keyFile := filepath.Join(dir, "cert.key")
+	now := time.Now()
+
+	write := func(created time.Time, validity time.Duration) {
+		certPEM, keyPEM, err := selfSigned(created, validity)
+		assert.NoError(t, err)
+		assert.NoError(t, os.WriteFile(keyFile, keyPEM, 0600))
+		assert.NoError(t, os.WriteFile(certFile, certPEM, 0644))
+	}
+	leaf := func() *x509.Certificate {
+		certPEM, err := os.ReadFile(certFile)
+		assert.NoError(t, err)
+		block, _ := pem.Decode(certPEM)
+		c, err := x509.ParseCertificate(block.Bytes)
+		assert.NoError(t, err)
+		return c
+	}
+
+	tests := []struct {
+		name     string
+		created  time.Time
+		validity time.Duration
+		renew    bool
+	}{
+		{"valid", now.AddDate(0, 0, -100), selfSignedValidity, false},
+		{"expires soon", now.AddDate(0, 0, -380), selfSignedValidity, true},
+		{"expired", now.AddDate(-2, 0, 0), selfSignedValidity, true},
+		{"too long for iOS", now.AddDate(-1, 0, 0), 10 * 365 * 24 * time.Hour, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			write(tt.created, tt.validity)
+			created, err := ensureSelfSigned(certFile, keyFile, now)
+			assert.NoError(t, err)
+			assert.EqualValues(t, tt.renew, created)
+			c := leaf()
+			if tt.renew {
+				assert.True(t, c.NotAfter.After(now.Add(renewBefore)))
+			}
+			assert.LessOrEqual(t, c.NotAfter.Sub(c.NotBefore), maxValidity)
+		})
+	}
+
+	// files which don't contain a certificate are not overwritten
+	assert.NoError(t, os.WriteFile(certFile, []byte("broken"), 0644))
+	_, err := ensureSelfSigned(certFile, keyFile, now)
+	assert.Error(t, err)
+	b, err := os.ReadFile(certFile)
+	assert.NoError(t, err)
+	assert.EqualValues(t, "broken", string(b))
+}
+
+func TestNewSelfSignedReloader_Renew(t *testing.T) {
+	dir := t.TempDir()
+	certFile := filepath.Join(dir, "cert.pem")
+	keyFile := filepath.Join(dir, "cert.key")
+
+	cr, err := NewSelfSignedReloader(certFile, keyFile)
+	assert.NoError(t, err)
+	cr.interval = 0
+
+	// the certificate expires while the server is running
+	certPEM, keyPEM, err := selfSigned(time.Now().AddDate(0, 0, -390), selfSignedValidity)
+	assert.NoError(t, err)
+	assert.NoError(t, os.WriteFile(keyFile, keyPEM, 0600))
+	assert.NoError(t, os.WriteFile(certFile, certPEM, 0644))
+
+	cert, err := cr.GetCertificate(nil)
+	assert.NoError(t, err)
+	leaf, err := x509.ParseCertificate(cert.Certificate[0])
+	assert.NoError(t, err)
+	assert.True(t, leaf.NotAfter.After(time.Now().Add(renewBefore)))
+}
+
+func TestNewCertReloader_Missing(t *testing.T) {
+	dir := t.TempDir()
+	_, err := NewCertReloader(filepath.Join(dir, "cert.pem"), filepath.Join(dir, "cert.key"))
+	assert.Error(t, err)
+}
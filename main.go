@@ -2,98 +2,325 @@ package main
 
 import (
 	"context"
+	"errors"
 	"flag"
+	"fmt"
 	"github.com/hneemann/session"
 	"github.com/hneemann/session/fileSys"
+	"github.com/hneemann/shopping/config"
 	"github.com/hneemann/shopping/item"
+	"github.com/hneemann/shopping/listen"
+	"github.com/hneemann/shopping/metrics"
 	"github.com/hneemann/shopping/server"
 	"log"
+	"log/slog"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
 	"strconv"
+	"sync/atomic"
+	"syscall"
 	"time"
 )
 
-type persist struct{}
+// userFileSystem is the file system of a user. It remembers the
+// name of the user, so that the name can be shown in the audit trail.
+type userFileSystem struct {
+	fileSys.FileSystem
+	user string
+}
+
+func userFileSystemFactory(folder string) session.FileSystemFactory {
+	factory := session.NewFileSystemFactory(folder)
+	return func(user string, create bool) (fileSys.FileSystem, error) {
+		f, err := factory(user, create)
+		if err != nil {
+			return nil, err
+		}
+		return userFileSystem{FileSystem: f, user: user}, nil
+	}
+}
+
+func setUser(f fileSys.FileSystem, a *item.Account) {
+	a.SetUser(userOf(f))
+}
+
+func userOf(f fileSys.FileSystem) string {
+	if uf, ok := f.(userFileSystem); ok {
+		return uf.user
+	}
+	return ""
+}
+
+var (
+	persistDuration = metrics.NewHistogram("shopping_persist_duration_seconds",
+		"Duration of loading and saving the data of a user.", metrics.DefaultBuckets, "op")
+	persistFailures = metrics.NewCounter("shopping_persist_failures_total",
+		"Number of failed loads and saves of the data of a user.", "op")
+	activeSessions atomic.Int64
+	_              = metrics.NewGaugeFunc("shopping_sessions_active",
+		"Number of sessions whose data is held in memory.",
+		func() float64 { return float64(activeSessions.Load()) })
+)
+
+// persist loads and stores the accounts. All accounts held in memory
+// are registered at the scheduler, if there is one, and at the flusher.
+type persist struct {
+	scheduler *item.Scheduler
+	flusher   *item.Flusher
+}
 
-func (p persist) Load(f fileSys.FileSystem) (*item.ListData, error) {
+func (p persist) register(f fileSys.FileSystem, a *item.Account) {
+	setUser(f, a)
+	activeSessions.Add(1)
+	if p.scheduler != nil {
+		p.scheduler.Add(a)
+	}
+	p.flusher.Add(a, func(a *item.Account) error {
+		return save(f, a)
+	})
+}
+
+func (p persist) Load(f fileSys.FileSystem) (*item.Account, error) {
+	start := time.Now()
+	a, err := load(f)
+	persistDuration.Since(start, "load")
+	if err != nil {
+		persistFailures.Inc("load")
+		return nil, err
+	}
+	p.register(f, a)
+	return a, nil
+}
+
+func load(f fileSys.FileSystem) (*item.Account, error) {
 	r, err := f.Reader("data.json")
 	if err != nil {
 		return nil, err
 	}
 	defer fileSys.CloseLog(r)
-	return item.Load(r)
+	return item.LoadAccount(r)
 }
 
-func (p persist) Init(_ fileSys.FileSystem, _ *item.ListData) error {
+func (p persist) Init(f fileSys.FileSystem, a *item.Account) error {
+	p.register(f, a)
 	return nil
 }
 
-func (p persist) Save(f fileSys.FileSystem, items *item.ListData) error {
+// Save is called by the session cache before the data is removed from memory
+func (p persist) Save(f fileSys.FileSystem, a *item.Account) error {
+	activeSessions.Add(-1)
+	if p.scheduler != nil {
+		p.scheduler.Remove(a)
+	}
+	p.flusher.Remove(a)
+	a.Lock()
+	defer a.Unlock()
+	start := time.Now()
+	err := save(f, a)
+	persistDuration.Since(start, "save")
+	if err != nil {
+		persistFailures.Inc("save")
+		slog.Error("could not save data", "user", userOf(f), "err", err)
+	}
+	return err
+}
+
+func save(f fileSys.FileSystem, a *item.Account) error {
 	w, err := f.Writer("data.json")
 	if err != nil {
 		return err
 	}
 	defer fileSys.CloseLog(w)
-	return items.Save(w)
+	return a.Save(w)
 }
 
 func main() {
-	dataFolder := flag.String("folder", "data", "data folder")
-	port := flag.Int("port", 8090, "port")
-	cert := flag.String("cert", "cert.pem", "certificate")
-	key := flag.String("key", "cert.key", "certificate")
-	debug := flag.Bool("debug", false, "starts server in debug mode")
+	configFile := flag.String("config", os.Getenv("SHOPPING_CONFIG"), "JSON config file")
+	def := config.Default()
+	dataFolder := flag.String("folder", def.Folder, "data folder")
+	port := flag.Int("port", def.Port, "port")
+	cert := flag.String("cert", def.Cert, "certificate")
+	key := flag.String("key", def.Key, "certificate")
+	mode := flag.String("mode", def.Mode, "tls: use the given certificate, selfsigned: create and renew a self-signed certificate, http: plain http behind a reverse proxy")
+	trustedProxies := flag.String("trustedProxies", def.TrustedProxies, "addresses of the reverse proxies whose X-Forwarded headers are trusted in http mode")
+	debug := flag.Bool("debug", def.Debug, "starts server in debug mode")
 	flag.Parse()
 
-	sc := session.NewSessionCache[item.ListData](
-		session.NewFileManager[item.ListData](
-			session.NewFileSystemFactory(*dataFolder),
-			persist{}),
-		8*24*time.Hour, 30*time.Minute)
-	defer sc.Close()
+	conf, err := config.ReadFile(*configFile)
+	if err != nil {
+		log.Fatal(err)
+	}
+	err = conf.ApplyEnv(os.LookupEnv)
+	if err != nil {
+		log.Fatal(err)
+	}
+	// flags given explicitly override the config file and the environment
+	flag.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "folder":
+			conf.Folder = *dataFolder
+		case "port":
+			conf.Port = *port
+		case "cert":
+			conf.Cert = *cert
+		case "key":
+			conf.Key = *key
+		case "mode":
+			conf.Mode = *mode
+		case "trustedProxies":
+			conf.TrustedProxies = *trustedProxies
+		case "debug":
+			conf.Debug = *debug
+		}
+	})
+	err = conf.Validate()
+	if err != nil {
+		log.Fatal("invalid configuration:\n", err)
+	}
+	slog.SetDefault(conf.Logger(os.Stderr))
+
+	item.SetHistoryRetention(time.Duration(conf.HistoryRetention))
+
+	var scheduler *item.Scheduler
+	if conf.Features.AutoCheckout {
+		scheduler = item.NewScheduler(time.Minute)
+	}
+	flusher := item.NewFlusher(time.Duration(conf.FlushDelay))
+
+	sc := session.NewSessionCache[item.Account](
+		session.NewFileManager[item.Account](
+			userFileSystemFactory(conf.Folder),
+			persist{scheduler: scheduler, flusher: flusher}),
+		time.Duration(conf.SessionLifetime), time.Duration(conf.DataLifetime))
+
+	health := server.NewHealth(func() error {
+		s, err := os.Stat(conf.Folder)
+		if err != nil {
+			return err
+		}
+		if !s.IsDir() {
+			return fmt.Errorf("%s is not a directory", conf.Folder)
+		}
+		return nil
+	})
 
 	mux := http.NewServeMux()
-	mux.HandleFunc("/login", sc.LoginHandler(server.Templates.Lookup("login.html")))
-	mux.HandleFunc("/logout", sc.LogoutHandler(server.Templates.Lookup("logout.html")))
-	mux.HandleFunc("/register", sc.RegisterHandler(server.Templates.Lookup("register.html")))
-	mux.HandleFunc("/", sc.CheckSessionFunc(server.MainHandler))
-	mux.HandleFunc("/table/", sc.CheckSessionRest(http.HandlerFunc(server.TableHandler)))
-	mux.HandleFunc("/add/", sc.CheckSessionFunc(server.AddHandler))
+	mux.HandleFunc("/healthz", health.Healthz)
+	mux.HandleFunc("/readyz", health.Readyz)
+	mux.HandleFunc("/login", server.Instrument("LoginHandler", sc.LoginHandler(server.Templates.Lookup("login.html"))))
+	mux.HandleFunc("/logout", server.Instrument("LogoutHandler", sc.LogoutHandler(server.Templates.Lookup("logout.html"))))
+	if conf.Features.Registration {
+		mux.HandleFunc("/register", server.Instrument("RegisterHandler", sc.RegisterHandler(server.Templates.Lookup("register.html"))))
+	}
+	mux.HandleFunc("/", server.Instrument("MainHandler", sc.CheckSessionFunc(server.CSRF(server.SelectList(server.MainHandler)))))
+	mux.HandleFunc("/table/", server.Instrument("TableHandler", sc.CheckSessionRest(server.CSRF(server.SelectList(server.TableHandler)))))
+	mux.HandleFunc("/add/", server.Instrument("AddHandler", sc.CheckSessionFunc(server.CSRF(server.SelectList(server.AddHandler)))))
 
-	mux.HandleFunc("/listAll", sc.CheckSessionFunc(server.ListAllHandler))
-	mux.HandleFunc("/listAllMod/", sc.CheckSessionRest(http.HandlerFunc(server.ListAllModHandler)))
-	mux.HandleFunc("/edit/", sc.CheckSessionFunc(server.EditHandler))
+	mux.HandleFunc("/listAll", server.Instrument("ListAllHandler", sc.CheckSessionFunc(server.CSRF(server.SelectList(server.ListAllHandler)))))
+	mux.HandleFunc("/listAllMod/", server.Instrument("ListAllModHandler", sc.CheckSessionRest(server.CSRF(server.SelectList(server.ListAllModHandler)))))
+	mux.HandleFunc("/edit/", server.Instrument("EditHandler", sc.CheckSessionFunc(server.CSRF(server.SelectList(server.EditHandler)))))
+	mux.HandleFunc("/recipes", server.Instrument("RecipesHandler", sc.CheckSessionFunc(server.CSRF(server.SelectList(server.RecipesHandler)))))
+	mux.HandleFunc("/recipe/", server.Instrument("RecipeHandler", sc.CheckSessionFunc(server.CSRF(server.SelectList(server.RecipeHandler)))))
+	mux.HandleFunc("/mealPlan", server.Instrument("MealPlanHandler", sc.CheckSessionFunc(server.CSRF(server.SelectList(server.MealPlanHandler)))))
+	mux.HandleFunc("/pantry", server.Instrument("PantryHandler", sc.CheckSessionFunc(server.CSRF(server.SelectList(server.PantryHandler)))))
+	mux.HandleFunc("/useSoon", server.Instrument("UseSoonHandler", sc.CheckSessionFunc(server.CSRF(server.SelectList(server.UseSoonHandler)))))
+	mux.HandleFunc("/template", server.Instrument("ListTemplateHandler", sc.CheckSessionFunc(server.CSRF(server.SelectList(server.ListTemplateHandler)))))
+	mux.HandleFunc("/settings", server.Instrument("SettingsHandler", sc.CheckSessionFunc(server.CSRF(server.SelectList(server.SettingsHandler)))))
+	mux.HandleFunc("/audit", server.Instrument("AuditHandler", sc.CheckSessionFunc(server.CSRF(server.SelectList(server.AuditHandler)))))
+	mux.HandleFunc("/lists", server.Instrument("ListsHandler", sc.CheckSessionFunc(server.CSRF(server.ListsHandler))))
 
 	assetServer := http.FileServer(http.FS(server.AssetFS))
-	if *debug {
-		log.Println("Starting in debug mode!")
+	if conf.Debug {
+		slog.Warn("starting in debug mode")
 	} else {
 		assetServer = Cache(assetServer)
 	}
 	mux.Handle("/assets/", assetServer)
+	if conf.Features.Metrics {
+		mux.Handle("/metrics", metrics.Handler())
+	}
 
-	serv := &http.Server{Addr: ":" + strconv.Itoa(*port), Handler: mux}
+	serv := &http.Server{Addr: ":" + strconv.Itoa(conf.Port), Handler: mux}
 
+	shutdownDone := make(chan struct{})
 	c := make(chan os.Signal, 1)
-	signal.Notify(c, os.Interrupt)
+	signal.Notify(c, os.Interrupt, syscall.SIGTERM)
 	go func() {
-		<-c
-		log.Print("interrupted")
+		s := <-c
+		slog.Info("shutting down", "signal", s.String(), "delay", time.Duration(conf.ShutdownDelay).String(),
+			"timeout", time.Duration(conf.ShutdownTimeout).String())
+		health.ShuttingDown()
+		// keep serving until the failing readiness probe is noticed,
+		// a second signal skips the delay
+		select {
+		case <-time.After(time.Duration(conf.ShutdownDelay)):
+		case <-c:
+		}
 
-		err := serv.Shutdown(context.Background())
+		ctx, cancel := context.WithTimeout(context.Background(), time.Duration(conf.ShutdownTimeout))
+		err := serv.Shutdown(ctx)
+		cancel()
 		if err != nil {
-			log.Println(err)
+			slog.Error("not all requests completed", "err", err)
 		}
+		close(shutdownDone)
 		for {
 			<-c
 		}
 	}()
 
-	err := serv.ListenAndServeTLS(*cert, *key)
-	if err != nil {
-		log.Println(err)
+	switch conf.Mode {
+	case "http":
+		var trusted []*net.IPNet
+		trusted, err = listen.ParseNetworks(conf.TrustedProxies)
+		if err != nil {
+			log.Fatal(err)
+		}
+		serv.Handler = listen.Forwarded(trusted, mux)
+		slog.Info("serving plain http", "port", conf.Port)
+		err = serv.ListenAndServe()
+	case "selfsigned", "tls":
+		var cr *listen.CertReloader
+		if conf.Mode == "selfsigned" {
+			cr, err = listen.NewSelfSignedReloader(conf.Cert, conf.Key)
+		} else {
+			cr, err = listen.NewCertReloader(conf.Cert, conf.Key)
+		}
+		if err != nil {
+			log.Fatal(err)
+		}
+		serv.TLSConfig = cr.TLSConfig()
+		err = serv.ListenAndServeTLS("", "")
+	default:
+		log.Fatal("unknown mode ", conf.Mode)
+	}
+	if errors.Is(err, http.ErrServerClosed) {
+		// wait until the running requests are completed
+		<-shutdownDone
+	} else if err != nil {
+		slog.Error("server failed", "err", err)
+	}
+
+	if scheduler != nil {
+		scheduler.Close()
+	}
+	flusher.Close()
+	closeSessions(sc)
+}
+
+// closeSessions writes the data of all sessions to disk and
+// reports whether this was successful
+func closeSessions(sc *session.Cache[item.Account]) {
+	sessions := activeSessions.Load()
+	failures := persistFailures.Value("save")
+	sc.Close()
+	failed := persistFailures.Value("save") - failures
+	if failed > 0 {
+		slog.Error("could not write all session data", "sessions", sessions, "failed", failed)
+	} else {
+		slog.Info("session data flushed", "sessions", sessions)
 	}
 }
 
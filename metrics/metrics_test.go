@@ -0,0 +1,74 @@
+package metrics
+
+import (
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func scrape(r *Registry) string {
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	return w.Body.String()
+}
+
+func TestCounter(t *testing.T) {
+	r := NewRegistry()
+	c := r.NewCounter("requests_total", "Number of requests.", "handler", "code")
+	c.Inc("main", "200")
+	c.Inc("main", "200")
+	c.Add(3, "table", "400")
+	plain := r.NewCounter("paid_total", "Number of trips.")
+
+	assert.EqualValues(t, 2, c.Value("main", "200"))
+	assert.EqualValues(t, 0, plain.Value())
+	assert.EqualValues(t, `# HELP requests_total Number of requests.
+# TYPE requests_total counter
+requests_total{handler="main",code="200"} 2
+requests_total{handler="table",code="400"} 3
+# HELP paid_total Number of trips.
+# TYPE paid_total counter
+paid_total 0
+`, scrape(r))
+
+	assert.Panics(t, func() { c.Inc("main") })
+	assert.Panics(t, func() { r.NewCounter("paid_total", "again") })
+}
+
+func TestHistogram(t *testing.T) {
+	r := NewRegistry()
+	h := r.NewHistogram("duration_seconds", "Duration.", []float64{0.1, 1}, "op")
+	h.Observe(0.05, "load")
+	h.Observe(0.5, "load")
+	h.Observe(5, "load")
+
+	assert.EqualValues(t, 3, h.Count("load"))
+	assert.EqualValues(t, 0, h.Count("save"))
+	assert.EqualValues(t, `# HELP duration_seconds Duration.
+# TYPE duration_seconds histogram
+duration_seconds_bucket{op="load",le="0.1"} 1
+duration_seconds_bucket{op="load",le="1"} 2
+duration_seconds_bucket{op="load",le="+Inf"} 3
+duration_seconds_sum{op="load"} 5.55
+duration_seconds_count{op="load"} 3
+`, scrape(r))
+}
+
+func TestGaugeFunc(t *testing.T) {
+	r := NewRegistry()
+	n := 0
+	r.NewGaugeFunc("sessions", "Active sessions.", func() float64 { return float64(n) })
+	n = 4
+	assert.EqualValues(t, `# HELP sessions Active sessions.
+# TYPE sessions gauge
+sessions 4
+`, scrape(r))
+}
+
+func TestLabelEscaping(t *testing.T) {
+	r := NewRegistry()
+	c := r.NewCounter("c", "Escaping.", "name")
+	c.Inc("a\"b\\c")
+	assert.Contains(t, scrape(r), `c{name="a\"b\\c"} 1`)
+}
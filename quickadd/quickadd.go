@@ -0,0 +1,175 @@
+// Package quickadd parses single line entries like "2 Dosen Tomaten",
+// "500 g Hack @Metzger" or "3x Joghurt" and adds them to the shopping list.
+package quickadd
+
+import (
+	"errors"
+	"fmt"
+	"github.com/hneemann/shopping/item"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Entry is a parsed quick-add entry
+type Entry struct {
+	// Quantity is the quantity given, zero if no quantity was given
+	Quantity float64
+	// Unit is the singular form of the unit given, empty if no unit was given
+	Unit string
+	Name string
+	Shop string
+}
+
+// KnownUnit checks if a word is a unit and returns its singular form
+type KnownUnit func(word string) (string, bool)
+
+// Parse parses the given text.
+// The syntax is [quantity[x]] [unit] name [quantity[x]] [@shop]
+func Parse(text string, knownUnit KnownUnit) (Entry, error) {
+	var e Entry
+	text = strings.TrimSpace(text)
+	if p := strings.Index(text, "@"); p >= 0 {
+		e.Shop = strings.TrimSpace(text[p+1:])
+		text = text[:p]
+	}
+
+	tokens := strings.Fields(text)
+	if len(tokens) > 1 {
+		if q, unit, ok, err := parseQuantity(tokens[0], knownUnit); ok {
+			if err != nil {
+				return Entry{}, err
+			}
+			e.Quantity = q
+			e.Unit = unit
+			tokens = tokens[1:]
+			if len(tokens) > 1 && strings.EqualFold(tokens[0], "x") {
+				tokens = tokens[1:]
+			}
+		} else if q, unit, ok, err := parseQuantity(tokens[len(tokens)-1], knownUnit); ok {
+			if err != nil {
+				return Entry{}, err
+			}
+			e.Quantity = q
+			e.Unit = unit
+			tokens = tokens[:len(tokens)-1]
+		}
+	} else if len(tokens) == 1 {
+		if _, _, ok, _ := parseQuantity(tokens[0], knownUnit); ok {
+			return Entry{}, errors.New("kein Artikel angegeben")
+		}
+	}
+
+	if e.Unit == "" && len(tokens) > 1 {
+		if unit, ok := knownUnit(tokens[0]); ok {
+			e.Unit = unit
+			tokens = tokens[1:]
+		}
+	}
+
+	e.Name = strings.Join(tokens, " ")
+	if e.Name == "" {
+		return Entry{}, errors.New("kein Artikel angegeben")
+	}
+	return e, nil
+}
+
+// parseQuantity parses tokens like "3", "1,5", "3x", "x3" or "500g".
+// If the token is not a quantity at all, ok is false.
+func parseQuantity(token string, knownUnit KnownUnit) (q float64, unit string, ok bool, err error) {
+	lt := strings.ToLower(token)
+	if strings.HasPrefix(lt, "x") && len(lt) > 1 && isDigit(lt[1]) {
+		lt = lt[1:]
+	}
+	n := 0
+	for n < len(lt) && (isDigit(lt[n]) || lt[n] == '.' || lt[n] == ',') {
+		n++
+	}
+	if n == 0 {
+		return 0, "", false, nil
+	}
+	rest := lt[n:]
+	if rest != "" && rest != "x" {
+		u, isUnit := knownUnit(token[len(token)-len(rest):])
+		if !isUnit {
+			return 0, "", false, nil
+		}
+		unit = u
+	}
+	q, err = strconv.ParseFloat(strings.ReplaceAll(lt[:n], ",", "."), 64)
+	if err != nil {
+		return 0, "", true, fmt.Errorf("ungültige Menge '%s'", token)
+	}
+	if q <= 0 {
+		return 0, "", true, fmt.Errorf("die Menge '%s' muss größer als Null sein", token)
+	}
+	return q, unit, true, nil
+}
+
+func isDigit(b byte) bool {
+	return unicode.IsDigit(rune(b))
+}
+
+// Add adds the parsed entry to the list. If there is a matching item, its
+// required quantity is increased, otherwise a new item is created.
+// An error is returned if the unit given can not be converted to the
+// unit of the matching item.
+func Add(ld *item.ListData, e Entry) (*item.Item, error) {
+	if i := ld.FindItem(e.Name, e.Unit); i != nil {
+		q := e.Quantity
+		if q == 0 {
+			q = i.Increment()
+		} else if e.Unit != "" {
+			var ok bool
+			q, ok = convert(q, e.Unit, i.UnitSingular())
+			if !ok {
+				return nil, fmt.Errorf("'%s' kann nicht in die Einheit '%s' von '%s' umgerechnet werden", e.Unit, i.Unit(), i.Name)
+			}
+		}
+		if e.Shop != "" && len(i.Shops) > 0 && !i.ShopIs(e.Shop) {
+			i.Shops = append(i.Shops, e.Shop)
+		}
+		ld.ModQuantity(i.Id, q, false)
+		return i, nil
+	}
+
+	var shops []string
+	if e.Shop != "" {
+		shops = []string{e.Shop}
+	}
+	q := e.Quantity
+	if q == 0 {
+		q = 1
+	}
+	cats := ld.Categories()
+	i := item.New(e.Name, e.Unit, 0, "", 0, "", cats[len(cats)-1], shops)
+	i.SetQuantity(q)
+	ld.AddItem(i)
+	return i, nil
+}
+
+var unitFactors = map[string]struct {
+	dimension string
+	factor    float64
+}{
+	"g":     {"mass", 1},
+	"kg":    {"mass", 1000},
+	"kilo":  {"mass", 1000},
+	"ml":    {"volume", 1},
+	"l":     {"volume", 1000},
+	"liter": {"volume", 1000},
+}
+
+// convert converts the quantity q given in the unit from to the unit to.
+// If a conversion is not possible, ok is false.
+func convert(q float64, from, to string) (float64, bool) {
+	if strings.EqualFold(from, to) {
+		return q, true
+	}
+	f, okf := unitFactors[strings.ToLower(from)]
+	t, okt := unitFactors[strings.ToLower(to)]
+	if okf && okt && f.dimension == t.dimension {
+		return q * f.factor / t.factor, true
+	}
+	return 0, false
+}
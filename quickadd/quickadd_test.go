@@ -0,0 +1,148 @@
+package quickadd
+
+import (
+	"github.com/hneemann/shopping/item"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func testList() *item.ListData {
+	ld := &item.ListData{CategoriesString: "Obst; Kühlregal; Fleisch; Anderes"}
+	ld.AddItem(item.New("Tomaten", "Dose", 400, "", 0, "", "Anderes", nil))
+	ld.AddItem(item.New("Hack", "g", 0, "", 0, "", "Fleisch", []string{"Aldi"}))
+	ld.AddItem(item.New("Joghurt", "Becher", 150, "", 0, "", "Kühlregal", nil))
+	ld.AddItem(item.New("Milch", "Packung", 1000, "", 1000, "", "Kühlregal", nil))
+	ld.AddItem(item.New("Milch", "Flasche", 1000, "", 1000, "", "Kühlregal", nil))
+	ld.AddItem(item.New("Äpfel", "kg", 0, "", 0, "", "Obst", nil))
+	return ld
+}
+
+func TestParse(t *testing.T) {
+	ld := testList()
+	tests := []struct {
+		text string
+		want Entry
+		err  bool
+	}{
+		{"Milch", Entry{Name: "Milch"}, false},
+		{"  Milch  ", Entry{Name: "Milch"}, false},
+		{"2 Dosen Tomaten", Entry{Quantity: 2, Unit: "Dose", Name: "Tomaten"}, false},
+		{"1 Dose Tomaten", Entry{Quantity: 1, Unit: "Dose", Name: "Tomaten"}, false},
+		{"Dose Tomaten", Entry{Unit: "Dose", Name: "Tomaten"}, false},
+		{"500 g Hack @Metzger", Entry{Quantity: 500, Unit: "g", Name: "Hack", Shop: "Metzger"}, false},
+		{"500g Hack", Entry{Quantity: 500, Unit: "g", Name: "Hack"}, false},
+		{"500 G Hack", Entry{Quantity: 500, Unit: "g", Name: "Hack"}, false},
+		{"1,5 kg Äpfel", Entry{Quantity: 1.5, Unit: "kg", Name: "Äpfel"}, false},
+		{"1.5 kg Äpfel", Entry{Quantity: 1.5, Unit: "kg", Name: "Äpfel"}, false},
+		{"3x Joghurt", Entry{Quantity: 3, Name: "Joghurt"}, false},
+		{"3X Joghurt", Entry{Quantity: 3, Name: "Joghurt"}, false},
+		{"3 x Joghurt", Entry{Quantity: 3, Name: "Joghurt"}, false},
+		{"x3 Joghurt", Entry{Quantity: 3, Name: "Joghurt"}, false},
+		{"3 Becher Joghurt", Entry{Quantity: 3, Unit: "Becher", Name: "Joghurt"}, false},
+		{"Joghurt 3", Entry{Quantity: 3, Name: "Joghurt"}, false},
+		{"Joghurt 3x", Entry{Quantity: 3, Name: "Joghurt"}, false},
+		{"Tomaten 2 Dosen", Entry{Name: "Tomaten 2 Dosen"}, false},
+		{"2 Flaschen Milch @ Edeka Center", Entry{Quantity: 2, Unit: "Flasche", Name: "Milch", Shop: "Edeka Center"}, false},
+		{"2 Gläser saure Gurken", Entry{Quantity: 2, Unit: "Glas", Name: "saure Gurken"}, false},
+		{"2 grüne Bohnen", Entry{Quantity: 2, Name: "grüne Bohnen"}, false},
+		{"7Up", Entry{Name: "7Up"}, false},
+		{"2 7Up", Entry{Quantity: 2, Name: "7Up"}, false},
+		{"Dose", Entry{Name: "Dose"}, false},
+		{"Kerzen @", Entry{Name: "Kerzen"}, false},
+		{"", Entry{}, true},
+		{"   ", Entry{}, true},
+		{"@Aldi", Entry{}, true},
+		{"3", Entry{}, true},
+		{"3x", Entry{}, true},
+		{"0 Milch", Entry{}, true},
+		{"1,2,3 Milch", Entry{}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			got, err := Parse(tt.text, ld.KnownUnit)
+			if tt.err {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+				assert.EqualValues(t, tt.want, got)
+			}
+		})
+	}
+}
+
+func TestAdd(t *testing.T) {
+	tests := []struct {
+		text     string
+		name     string
+		unit     string
+		quantity float64
+		shops    []string
+		created  bool
+		err      bool
+	}{
+		{"2 Dosen Tomaten", "Tomaten", "Dose", 2, nil, false, false},
+		{"Tomate", "Tomaten", "Dose", 1, nil, false, false},
+		{"500 g Hack", "Hack", "g", 500, []string{"Aldi"}, false, false},
+		{"Hack", "Hack", "g", 50, []string{"Aldi"}, false, false},
+		{"0,5 kg Hack @Metzger", "Hack", "g", 500, []string{"Aldi", "Metzger"}, false, false},
+		{"3x Joghurt", "Joghurt", "Becher", 3, nil, false, false},
+		{"2 Flaschen Milch", "Milch", "Flasche", 2, nil, false, false},
+		{"Milch, Packung", "Milch", "Packung", 1, nil, false, false},
+		{"500 g äpfel", "Äpfel", "kg", 0.5, nil, false, false},
+		{"2 Gläser Gurken @Aldi", "Gurken", "Glas", 2, []string{"Aldi"}, true, false},
+		{"Kerzen", "Kerzen", "", 1, nil, true, false},
+		{"1 Dose Tomaten", "Tomaten", "Dose", 1, nil, false, false},
+		{"500 g Tomaten", "", "", 0, nil, false, true},
+		{"1 l Hack", "", "", 0, nil, false, true},
+		{"2 Flaschen Joghurt", "", "", 0, nil, false, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			ld := testList()
+			n := len(ld.Items)
+			e, err := Parse(tt.text, ld.KnownUnit)
+			assert.NoError(t, err)
+			i, err := Add(ld, e)
+			if tt.err {
+				assert.Error(t, err)
+				assert.Len(t, ld.Items, n)
+				for _, i := range ld.Items {
+					assert.EqualValues(t, 0, i.QuantityRequired, i.Name)
+				}
+				return
+			}
+			assert.NoError(t, err)
+			assert.EqualValues(t, tt.name, i.Name)
+			assert.EqualValues(t, tt.unit, i.UnitSingular())
+			assert.InDelta(t, tt.quantity, i.QuantityRequired, 1e-9)
+			assert.EqualValues(t, tt.shops, i.Shops)
+			if tt.created {
+				assert.Len(t, ld.Items, n+1)
+				assert.EqualValues(t, "Anderes", i.Category)
+			} else {
+				assert.Len(t, ld.Items, n)
+			}
+		})
+	}
+}
+
+func Test_convert(t *testing.T) {
+	tests := []struct {
+		q        float64
+		from, to string
+		want     float64
+		ok       bool
+	}{
+		{500, "g", "kg", 0.5, true},
+		{1.5, "l", "ml", 1500, true},
+		{2, "Dose", "dose", 2, true},
+		{2, "g", "ml", 0, false},
+		{2, "Dose", "g", 0, false},
+		{500, "g", "Dose", 0, false},
+	}
+	for _, tt := range tests {
+		got, ok := convert(tt.q, tt.from, tt.to)
+		assert.EqualValues(t, tt.ok, ok, "%s -> %s", tt.from, tt.to)
+		assert.EqualValues(t, tt.want, got, "%s -> %s", tt.from, tt.to)
+	}
+}
@@ -0,0 +1,28 @@
+function csrfToken() {
+    let m = document.cookie.match(/(?:^|;\s*)csrf=([^;]*)/);
+    if (m === null) {
+        return "";
+    }
+    return decodeURIComponent(m[1]);
+}
+
+function postForm(url, query) {
+    return fetch(url, {
+        method: "POST",
+        headers: {"X-CSRF-Token": csrfToken()},
+        body: new URLSearchParams(query),
+        signal: AbortSignal.timeout(3000)
+    })
+}
+
+// adds the csrf token to all forms which are posted
+document.addEventListener("submit", (evt) => {
+    let form = evt.target;
+    if (form.method.toLowerCase() === "post" && form.elements["csrf"] === undefined) {
+        let input = document.createElement("input");
+        input.type = "hidden";
+        input.name = "csrf";
+        input.value = csrfToken();
+        form.appendChild(input);
+    }
+}, true);
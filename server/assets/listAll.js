@@ -1,6 +1,11 @@
 function modify(id, n) {
-    fetch("/listAllMod/?id=" + id + "&n=" + n)
+    postForm("/listAllMod/", "id=" + id + "&n=" + n)
         .then(function (response) {
+            if (response.status === 400) {
+                return response.json().then(function (e) {
+                    alert("Ungültige Eingabe: " + e.error);
+                });
+            }
             if (response.status !== 200) {
                 window.location.reload();
                 return;
@@ -8,6 +13,9 @@ function modify(id, n) {
             return response.text()
         })
         .then(function (html) {
+            if (html === undefined) {
+                return;
+            }
             let q = document.getElementById('q' + id);
             q.innerHTML = html;
         })
@@ -15,8 +23,17 @@ function modify(id, n) {
 
 function saveCategories() {
     let cat = document.getElementById('categoriesInput').value;
-    fetch("/listAllMod/?cat=" + encodeURIComponent(cat))
+    postForm("/listAllMod/", "cat=" + encodeURIComponent(cat))
         .then(function (response) {
             window.location.reload();
         })
+}
+
+function tagChanged(showAll) {
+    let tag = document.getElementById('selectedTag').value;
+    let query = "?all=" + showAll;
+    if (tag !== "") {
+        query += "&tag=" + encodeURIComponent(tag);
+    }
+    window.location.href = "/listAll" + query;
 }
\ No newline at end of file
@@ -7,7 +7,7 @@ function showSetQuantity(quantity, id) {
     let u = getUnitById(id);
     increment = u.increment;
     document.getElementById("setQuantityUnit").innerHTML = u.unit;
-    document.getElementById('setQuantityQuantity').innerHTML = niceToString(quantity);
+    document.getElementById('setQuantityQuantity').value = niceToString(quantity);
     showPopUpById('setQuantity');
 }
 
@@ -28,18 +28,20 @@ function niceToString(v) {
 }
 
 function setQuantityMod(inc) {
-    let v = niceFromString(document.getElementById('setQuantityQuantity').innerHTML);
+    let v = niceFromString(document.getElementById('setQuantityQuantity').value);
+    if (isNaN(v)) {
+        v = 0;
+    }
     v += increment * inc;
     if (v <= 0) {
         v = increment;
     }
-    document.getElementById('setQuantityQuantity').innerHTML = niceToString(v);
+    document.getElementById('setQuantityQuantity').value = niceToString(v);
 }
 
 function setQuantityModify() {
-    let text = document.getElementById('setQuantityQuantity').innerHTML;
-    let v = niceFromString(text);
-    updateTable("id=" + quantityModifyId + "&mode=set&q=" + v)
+    let text = document.getElementById('setQuantityQuantity').value;
+    updateTable("id=" + quantityModifyId + "&mode=set&q=" + encodeURIComponent(text))
 }
 
 function setQuantityDelete() {
@@ -74,10 +76,11 @@ function addItemShow() {
 function addItemCatChanged() {
     var category = document.getElementById('category').value;
     var items = document.getElementById('items').getElementsByTagName('option');
+    let tag = getSelectedTag();
     let found = "";
     let id = "";
     for (var i = 0; i < items.length; i++) {
-        if (items[i].getAttribute("data-cat") === category) {
+        if (items[i].getAttribute("data-cat") === category && hasTag(items[i], tag)) {
             found += '<option value="' + items[i].id + '">' + items[i].value + '</option>';
             if (id === "") {
                 id = items[i].id;
@@ -156,11 +159,19 @@ function updateTable(query) {
             query += "s=" + shop;
         }
     }
+    let tag = getSelectedTag();
+    if (tag !== "") {
+        if (query !== "") {
+            query += "&";
+        }
+        query += "tag=" + encodeURIComponent(tag);
+    }
 
-    fetch("/table/?" + query, {
-        signal: AbortSignal.timeout(3000)
-    })
+    postForm("/table/", query)
         .then(function (response) {
+            if (response.status === 400) {
+                return response.json().then(showBadRequest);
+            }
             if (response.status !== 200) {
                 window.location.reload();
                 return;
@@ -171,20 +182,106 @@ function updateTable(query) {
             window.location.reload();
         })
         .then(function (html) {
+            if (html === undefined) {
+                return;
+            }
             let table = document.getElementById('table');
             table.innerHTML = html;
+            applyCollapsed();
         })
 }
 
+function showBadRequest(e) {
+    alert("Ungültige Eingabe: " + e.error);
+}
+
+function getSelectedTag() {
+    let tagElement = document.getElementById('selectedTag');
+    if (tagElement !== null) {
+        return tagElement.value;
+    }
+    return "";
+}
+
+function hasTag(item, tag) {
+    if (tag === "") {
+        return true;
+    }
+    let tags = item.getAttribute("data-tags");
+    if (tags === null || tags === "") {
+        return false;
+    }
+    tag = tag.toLowerCase();
+    return tags.split(", ").some(t => t.toLowerCase() === tag);
+}
+
+function getCollapsed() {
+    let c = localStorage.getItem("collapsed");
+    if (c === null) {
+        return [];
+    }
+    return JSON.parse(c);
+}
+
+function isCollapsed(collapsed, cat, inclusive) {
+    for (let c of collapsed) {
+        if ((inclusive && cat === c) || cat.startsWith(c + " > ")) {
+            return true;
+        }
+    }
+    return false;
+}
+
+function toggleCategory(cat) {
+    let collapsed = getCollapsed();
+    let i = collapsed.indexOf(cat);
+    if (i >= 0) {
+        collapsed.splice(i, 1);
+    } else {
+        collapsed.push(cat);
+    }
+    localStorage.setItem("collapsed", JSON.stringify(collapsed));
+    applyCollapsed();
+}
+
+function applyCollapsed() {
+    let collapsed = getCollapsed();
+    for (let row of document.querySelectorAll("tr[data-cat]")) {
+        row.hidden = isCollapsed(collapsed, row.getAttribute("data-cat"), true);
+    }
+    for (let row of document.querySelectorAll("tr[data-head]")) {
+        let cat = row.getAttribute("data-head");
+        row.hidden = isCollapsed(collapsed, cat, false);
+        if (collapsed.indexOf(cat) >= 0) {
+            row.classList.add("collapsed");
+        } else {
+            row.classList.remove("collapsed");
+        }
+    }
+}
+
+document.addEventListener("DOMContentLoaded", applyCollapsed);
+
 function addTemp() {
     let temp = document.getElementById('addTemp').value;
     if (temp.length > 0) {
-        updateTable("a=at&n=" + temp)
+        updateTable("a=at&n=" + encodeURIComponent(temp))
     }
 }
 
-function toggleTemp(n) {
-    updateTable("a=tt&n=" + n)
+function quickAddItem() {
+    let text = document.getElementById('quickAdd').value;
+    if (text.length > 0) {
+        updateTable("a=qa&n=" + encodeURIComponent(text))
+    }
+}
+
+function addTempForced(temp) {
+    updateTable("a=at&f=1&n=" + encodeURIComponent(temp))
+}
+
+function toggleTemp(id) {
+    updateTable("a=tt&tid=" + id)
 }
 
 
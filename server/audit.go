@@ -0,0 +1,20 @@
+package server
+
+import (
+	"github.com/hneemann/shopping/item"
+	"net/http"
+)
+
+const auditEntries = 100
+
+var auditTemp = Templates.Lookup("audit.html")
+
+// AuditHandler shows the latest changes of the list
+func AuditHandler(w http.ResponseWriter, r *http.Request) {
+	if data, ok := r.Context().Value("data").(*item.ListData); ok {
+		err := auditTemp.Execute(w, data.RecentAudit(auditEntries))
+		if err != nil {
+			data.Logger().Error("could not render page", "template", "audit.html", "err", err)
+		}
+	}
+}
@@ -0,0 +1,78 @@
+package server
+
+import (
+	"fmt"
+	"github.com/hneemann/shopping/item"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"net/http"
+	"net/url"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// TestConcurrentRequests runs requests of several devices in parallel to the
+// scheduler and the flusher. Run it with -race to detect unsynchronized access.
+func TestConcurrentRequests(t *testing.T) {
+	a := &item.Account{}
+	a.Lists = []*item.NamedList{{Name: "Zuhause", List: testList()}}
+	assert.NoError(t, a.AddList("Büro", true, a.Named("")))
+
+	scheduler := item.NewScheduler(time.Millisecond)
+	defer scheduler.Close()
+	scheduler.Add(a)
+	flusher := item.NewFlusher(2 * time.Millisecond)
+	defer flusher.Close()
+	flusher.Add(a, func(a *item.Account) error {
+		return a.Save(io.Discard)
+	})
+
+	post := func(h http.HandlerFunc, target string, form url.Values) {
+		w := call(SelectList(h), a, http.MethodPost, target, form)
+		assert.Less(t, w.Code, 400, target, form)
+	}
+	get := func(h http.HandlerFunc, target string) {
+		w := call(SelectList(h), a, http.MethodGet, target, nil)
+		assert.EqualValues(t, http.StatusOK, w.Code, target)
+	}
+
+	const devices = 6
+	const requests = 30
+	var added atomic.Int32
+	var wg sync.WaitGroup
+	for d := 0; d < devices; d++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < requests; i++ {
+				switch (d + i) % 8 {
+				case 0:
+					post(TableHandler, "/table/", url.Values{"id": {"1"}, "mode": {"add"}, "q": {"1"}})
+				case 1:
+					post(TableHandler, "/table/", url.Values{"id": {"1"}, "mode": {"car"}})
+				case 2:
+					post(TableHandler, "/table/", url.Values{"a": {"at"}, "n": {fmt.Sprintf("Zettel %d", i)}, "f": {"1"}})
+				case 3:
+					post(AddHandler, "/add/", url.Values{"name": {fmt.Sprintf("Brot %d-%d", d, i)}, "unit": {"Stück"}, "quantity": {"1"}})
+					added.Add(1)
+				case 4:
+					get(MainHandler, "/")
+				case 5:
+					get(ListAllHandler, "/listAll")
+				case 6:
+					get(TableHandler, "/table/")
+				case 7:
+					post(TableHandler, "/table/", url.Values{"a": {"paid"}})
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	a.Lock()
+	defer a.Unlock()
+	assert.EqualValues(t, 2+added.Load(), len(a.Named("").List.Items), "all items added")
+	assert.EqualValues(t, len(a.Lists[0].List.Items), len(a.Lists[1].List.Items), "catalog shared")
+}
@@ -0,0 +1,81 @@
+package server
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"log/slog"
+	"net/http"
+	"os"
+)
+
+const (
+	csrfCookie = "csrf"
+	csrfField  = "csrf"
+	csrfHeader = "X-CSRF-Token"
+)
+
+var csrfKey = func() []byte {
+	key := make([]byte, 32)
+	_, err := rand.Read(key)
+	if err != nil {
+		slog.Error("could not create csrf key", "err", err)
+		os.Exit(1)
+	}
+	return key
+}()
+
+// csrfToken returns the token belonging to the session of the request.
+// The token is derived from the session id, so there is no need to store it.
+func csrfToken(r *http.Request) string {
+	c, err := r.Cookie("id")
+	if err != nil || c.Value == "" {
+		return ""
+	}
+	mac := hmac.New(sha256.New, csrfKey)
+	mac.Write([]byte(c.Value))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// CSRF protects the parent handler against cross site request forgery.
+// All requests changing the state have to be POST requests carrying the
+// token of the session, either in the form field csrf or in the header
+// X-CSRF-Token. The token is passed to the scripts by the csrf cookie.
+func CSRF(parent http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		token := csrfToken(r)
+		switch r.Method {
+		case http.MethodGet, http.MethodHead:
+			if c, err := r.Cookie(csrfCookie); err != nil || c.Value != token {
+				http.SetCookie(w, &http.Cookie{
+					Name:     csrfCookie,
+					Value:    token,
+					Secure:   true,
+					SameSite: http.SameSiteStrictMode,
+					Path:     "/",
+				})
+			}
+		case http.MethodPost:
+			sent := r.Header.Get(csrfHeader)
+			if sent == "" {
+				sent = r.PostFormValue(csrfField)
+			}
+			if token == "" || !hmac.Equal([]byte(sent), []byte(token)) {
+				slog.Warn("rejected request without valid csrf token", "path", r.URL.Path, "remote", r.RemoteAddr)
+				http.Error(w, "Ungültiges CSRF-Token", http.StatusForbidden)
+				return
+			}
+		default:
+			methodNotAllowed(w)
+			return
+		}
+		parent(w, r)
+	}
+}
+
+// methodNotAllowed is used if a state changing request is not a POST request
+func methodNotAllowed(w http.ResponseWriter) {
+	w.Header().Set("Allow", http.MethodPost)
+	http.Error(w, "Nur POST erlaubt", http.StatusMethodNotAllowed)
+}
@@ -0,0 +1,123 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"github.com/hneemann/shopping/item"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+const testSession = "session-id"
+
+func csrfCall(h http.HandlerFunc, ld *item.ListData, method, target string, form url.Values, token string) *httptest.ResponseRecorder {
+	var r *http.Request
+	if form != nil {
+		r = httptest.NewRequest(method, target, strings.NewReader(form.Encode()))
+		r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	} else {
+		r = httptest.NewRequest(method, target, nil)
+	}
+	r.AddCookie(&http.Cookie{Name: "id", Value: testSession})
+	if token != "" {
+		r.Header.Set(csrfHeader, token)
+	}
+	r = r.WithContext(context.WithValue(r.Context(), "data", ld))
+	w := httptest.NewRecorder()
+	CSRF(h)(w, r)
+	return w
+}
+
+func listJSON(t *testing.T, ld *item.ListData) string {
+	var b bytes.Buffer
+	assert.NoError(t, ld.Save(&b))
+	return b.String()
+}
+
+func validToken() string {
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.AddCookie(&http.Cookie{Name: "id", Value: testSession})
+	return csrfToken(r)
+}
+
+func TestCSRF_Cookie(t *testing.T) {
+	w := csrfCall(MainHandler, testList(), http.MethodGet, "/", nil, "")
+	assert.EqualValues(t, http.StatusOK, w.Code)
+	cookies := w.Result().Cookies()
+	if assert.Len(t, cookies, 1) {
+		assert.EqualValues(t, csrfCookie, cookies[0].Name)
+		assert.EqualValues(t, validToken(), cookies[0].Value)
+	}
+}
+
+func TestCSRF_Token(t *testing.T) {
+	tests := []struct {
+		name  string
+		form  url.Values
+		token string
+		code  int
+		q     float64
+	}{
+		{"no token", url.Values{"id": {"1"}, "mode": {"add"}, "q": {"1"}}, "", http.StatusForbidden, 0},
+		{"wrong token", url.Values{"id": {"1"}, "mode": {"add"}, "q": {"1"}}, "abc", http.StatusForbidden, 0},
+		{"wrong field", url.Values{"id": {"1"}, "mode": {"add"}, "q": {"1"}, csrfField: {"abc"}}, "", http.StatusForbidden, 0},
+		{"header", url.Values{"id": {"1"}, "mode": {"add"}, "q": {"1"}}, validToken(), http.StatusOK, 1},
+		{"field", url.Values{"id": {"1"}, "mode": {"add"}, "q": {"1"}, csrfField: {validToken()}}, "", http.StatusOK, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ld := testList()
+			w := csrfCall(TableHandler, ld, http.MethodPost, "/table/", tt.form, tt.token)
+			assert.EqualValues(t, tt.code, w.Code)
+			assert.EqualValues(t, tt.q, ld.ItemById(1).QuantityRequired)
+		})
+	}
+}
+
+func TestCSRF_NoSession(t *testing.T) {
+	ld := testList()
+	r := httptest.NewRequest(http.MethodPost, "/table/", strings.NewReader("id=1&mode=add&q=1"))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	r = r.WithContext(context.WithValue(r.Context(), "data", ld))
+	w := httptest.NewRecorder()
+	CSRF(TableHandler)(w, r)
+	assert.EqualValues(t, http.StatusForbidden, w.Code)
+	assert.EqualValues(t, 0, ld.ItemById(1).QuantityRequired)
+}
+
+func TestCrossSiteGet(t *testing.T) {
+	tests := []struct {
+		handler http.HandlerFunc
+		target  string
+	}{
+		{TableHandler, "/table/?id=1&mode=add&q=1"},
+		{TableHandler, "/table/?id=1&mode=car"},
+		{TableHandler, "/table/?a=at&n=Kerzen&f=1"},
+		{TableHandler, "/table/?a=paid"},
+		{ListAllModHandler, "/listAllMod/?id=1&n=1"},
+		{ListAllModHandler, "/listAllMod/?cat=Alles;Nichts"},
+		{ListAllHandler, "/listAll?del=1"},
+		{RecipesHandler, "/recipes?del=1"},
+		{PantryHandler, "/pantry?id=1&a=empty"},
+		{SettingsHandler, "/settings?mode=off"},
+		{ListTemplateHandler, "/template?a=del&name=Grillen"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.target, func(t *testing.T) {
+			ld := testList()
+			ld.SetQuantity(2, 1)
+			ld.ToggleInCar(2)
+			ld.ItemById(1).TrackStock = true
+			ld.ItemById(1).Stock = 3
+			ld.AddRecipe("Apfelkuchen", 4)
+			ld.CreateTemplate("Grillen")
+			before := listJSON(t, ld)
+			csrfCall(tt.handler, ld, http.MethodGet, tt.target, nil, "")
+			assert.JSONEq(t, before, listJSON(t, ld))
+		})
+	}
+}
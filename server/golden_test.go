@@ -0,0 +1,72 @@
+package server
+
+import (
+	"flag"
+	"github.com/hneemann/shopping/item"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "update the golden files")
+
+// goldenList creates a list which uses most of the features shown by the
+// templates. It contains no history, so that the rendered pages do not
+// depend on the current date.
+func goldenList() *item.ListData {
+	ld := &item.ListData{CategoriesString: "Obst; Backwaren; Kühlregal"}
+	apples := item.New("Äpfel", "kg", 0, "", 0, "", "Obst", []string{"Markt"})
+	apples.Tags = []string{"bio"}
+	ld.AddItem(apples)
+	ld.AddItem(item.New("Bananen", "Stück", 120, "", 0, "", "Obst", nil))
+	ld.AddItem(item.New("Brot", "Laib", 750, "0,75kg", 0, "", "Backwaren", []string{"Bäcker"}))
+	ld.AddItem(item.New("Milch", "Packung", 1000, "", 1000, "1l", "Kühlregal", []string{"Markt", "Discounter"}))
+	ld.AddItem(item.New("Joghurt", "Becher", 150, "", 0, "", "Kühlregal", nil))
+	ld.SetQuantity(1, 1.5)
+	ld.SetQuantity(2, 6)
+	ld.SetQuantity(3, 1)
+	ld.SetQuantity(4, 2)
+	ld.ToggleInCar(2)
+	ld.ToggleAvailable(3)
+	ld.AddTemp("Kerzen", true)
+	return ld
+}
+
+// golden compares the body with the golden file testdata/<name>.golden.
+// The golden files are rewritten if the tests are run with -update.
+func golden(t *testing.T, name string, body []byte) {
+	t.Helper()
+	file := filepath.Join("testdata", name+".golden")
+	if *update {
+		assert.NoError(t, os.WriteFile(file, body, 0644))
+		return
+	}
+	want, err := os.ReadFile(file)
+	if assert.NoError(t, err, "run go test -update to create the golden file") {
+		assert.EqualValues(t, string(want), string(body))
+	}
+}
+
+func TestTemplatesGolden(t *testing.T) {
+	tests := []struct {
+		name    string
+		handler http.HandlerFunc
+		target  string
+	}{
+		{"main", MainHandler, "/"},
+		{"table", TableHandler, "/table/"},
+		{"tableShop", TableHandler, "/table/?s=Markt&h=0"},
+		{"listAll", ListAllHandler, "/listAll"},
+		{"edit", EditHandler, "/edit/?item=4"},
+		{"add", AddHandler, "/add/?c=Backwaren&t=all"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := call(tt.handler, goldenList(), http.MethodGet, tt.target, nil)
+			assert.EqualValues(t, http.StatusOK, w.Code)
+			golden(t, tt.name, w.Body.Bytes())
+		})
+	}
+}
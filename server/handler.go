@@ -3,11 +3,10 @@ package server
 import (
 	"embed"
 	"fmt"
-	"github.com/hneemann/parser2"
-	"github.com/hneemann/parser2/funcGen"
+	"github.com/hneemann/shopping/calc"
 	"github.com/hneemann/shopping/item"
+	"github.com/hneemann/shopping/quickadd"
 	"html/template"
-	"log"
 	"math"
 	"net/http"
 	"strconv"
@@ -24,32 +23,45 @@ var AssetFS embed.FS
 const eps = 1e-6
 
 var Templates = template.Must(template.New("").Funcs(map[string]any{
-	"formatDate": func(t time.Time) string {
-		age := ageDays(t)
-		if age < 6 {
-			switch age {
-			case 0:
-				return "heute"
-			case 1:
-				return "gestern"
-			case 2:
-				return "vorgestern"
-			default:
-				return fmt.Sprintf("vor %d Tagen", age)
+	"formatDate": formatDate,
+	"niceToStr":  niceToStr,
+}).ParseFS(templateFS, "templates/*.html"))
+
+// formatDate formats a date. Dates close to today are described relative to today.
+func formatDate(t time.Time) string {
+	age := ageDays(t)
+	if age < 6 && age > -6 {
+		switch age {
+		case 0:
+			return "heute"
+		case 1:
+			return "gestern"
+		case 2:
+			return "vorgestern"
+		case -1:
+			return "morgen"
+		case -2:
+			return "übermorgen"
+		default:
+			if age < 0 {
+				return fmt.Sprintf("in %d Tagen", -age)
 			}
+			return fmt.Sprintf("vor %d Tagen", age)
 		}
-		return t.Format("02.01.2006")
-	},
-	"niceToStr": func(v float64) string {
-		if math.Abs(math.Round(v)-v) < eps {
-			return fmt.Sprintf("%d", int(v))
-		}
-		if math.Abs(math.Round(v*10)-v*10) < eps {
-			return fmt.Sprintf("%.1f", v)
-		}
-		return fmt.Sprintf("%.2f", v)
-	},
-}).ParseFS(templateFS, "templates/*.html"))
+	}
+	return t.Format("02.01.2006")
+}
+
+// niceToStr formats a number using at most two decimal places
+func niceToStr(v float64) string {
+	if math.Abs(math.Round(v)-v) < eps {
+		return fmt.Sprintf("%d", int(v))
+	}
+	if math.Abs(math.Round(v*10)-v*10) < eps {
+		return fmt.Sprintf("%.1f", v)
+	}
+	return fmt.Sprintf("%.2f", v)
+}
 
 func ageDays(t time.Time) int {
 	return int(math.Round(toDay(time.Now()).Sub(toDay(t)).Hours() / 24))
@@ -64,177 +76,186 @@ var tableTemp = Templates.Lookup("table.html")
 var addTemp = Templates.Lookup("add.html")
 
 type mainData struct {
+	Account          *item.Account
+	ListName         string
 	ListData         *item.ListData
 	HideCart         bool
 	Categories       item.CategoryList
 	CategorySelected item.Category
 	Shop             string
 	Shops            []string
+	Tag              string
+	Tags             []string
+	TempName         string
+	TempMatches      []*item.Item
+	QuickAdd         string
+	Error            error
 }
 
 func MainHandler(w http.ResponseWriter, r *http.Request) {
 	if data, ok := r.Context().Value("data").(*item.ListData); ok {
 		categorySelected := data.Categories()[0]
 		err := mainTemp.Execute(w, mainData{
+			Account:          accountOf(r),
+			ListName:         listNameOf(r),
 			ListData:         data,
 			HideCart:         false,
 			Categories:       data.Categories(),
 			Shops:            data.Shops(),
+			Tags:             data.Tags(),
 			CategorySelected: categorySelected,
 		})
 		if err != nil {
-			log.Println(err)
+			data.Logger().Error("could not render page", "template", "main.html", "err", err)
 		}
 	}
 }
 
 func TableHandler(w http.ResponseWriter, r *http.Request) {
 	if data, ok := r.Context().Value("data").(*item.ListData); ok {
-		query := r.URL.Query()
-		shop := query.Get("s")
-		idStr := query.Get("id")
-		if idStr != "" {
-			id := toInt(idStr)
-			mode := query.Get("mode")
-			if data.IdValid(id) {
-				switch mode {
-				case "na":
-					(*data).ToggleAvailable(id)
-				case "car":
-					(*data).ToggleInCar(id)
-				case "del":
-					(*data).DeleteFromList(id)
-				case "set":
-					(*data).SetQuantity(id, toFloat(query.Get("q")))
-				case "add":
-					(*data).ModQuantity(id, toFloat(query.Get("q")), false)
+		p := formParams(r)
+		shop := r.FormValue("s")
+		tag := r.FormValue("tag")
+		var tempName string
+		var tempMatches []*item.Item
+		var quickAdd string
+		var err error
+		if r.Method != http.MethodPost && (p.Has("id") || p.Has("a")) {
+			methodNotAllowed(w)
+			return
+		}
+		if p.Has("id") {
+			id := p.Id("id", data)
+			mode := p.OneOf("mode", "na", "car", "del", "set", "add")
+			var q float64
+			if mode == "add" {
+				q = p.Float("q")
+			}
+			if p.Err() != nil {
+				writeBadRequest(w, p.Err())
+				return
+			}
+			switch mode {
+			case "na":
+				(*data).ToggleAvailable(id)
+			case "car":
+				(*data).ToggleInCar(id)
+			case "del":
+				(*data).DeleteFromList(id)
+			case "set":
+				q, err = toQuantity(r.FormValue("q"), data, data.ItemById(id))
+				if err == nil {
+					(*data).SetQuantity(id, q)
 				}
+			case "add":
+				(*data).ModQuantity(id, q, false)
 			}
 		} else {
-			action := query.Get("a")
+			action := p.OneOf("a", "", "paid", "revert", "ack", "at", "qa", "tt")
+			var tempId int
+			if action == "tt" {
+				tempId = p.TempId("tid", data)
+			}
+			if p.Err() != nil {
+				writeBadRequest(w, p.Err())
+				return
+			}
 			switch action {
 			case "paid":
 				data.Paid()
+			case "revert":
+				data.RevertAutoPaid()
+			case "ack":
+				data.AcknowledgeAutoPaid()
 			case "at":
-				(*data).AddTemp(query.Get("n"))
-			case "tt":
-				n, err := strconv.Atoi(query.Get("n"))
+				tempName = r.FormValue("n")
+				tempMatches = (*data).AddTemp(tempName, r.FormValue("f") == "1")
+			case "qa":
+				quickAdd = r.FormValue("n")
+				var e quickadd.Entry
+				e, err = quickadd.Parse(quickAdd, data.KnownUnit)
 				if err == nil {
-					(*data).ToggleTemp(n)
+					_, err = quickadd.Add(data, e)
+					if err == nil {
+						quickAdd = ""
+					}
 				}
+			case "tt":
+				(*data).ToggleTemp(tempId)
 			}
 		}
 
-		err := tableTemp.Execute(w, mainData{
-			ListData:   data,
-			Shop:       shop,
-			HideCart:   query.Get("h") != "0",
-			Categories: data.Categories(),
-			Shops:      data.Shops(),
+		err = tableTemp.Execute(w, mainData{
+			Account:     accountOf(r),
+			ListName:    listNameOf(r),
+			ListData:    data,
+			Shop:        shop,
+			Tag:         tag,
+			HideCart:    r.FormValue("h") != "0",
+			Categories:  data.Categories(),
+			Shops:       data.Shops(),
+			Tags:        data.Tags(),
+			TempName:    tempName,
+			TempMatches: tempMatches,
+			QuickAdd:    quickAdd,
+			Error:       err,
 		})
 		if err != nil {
-			log.Println(err)
+			data.Logger().Error("could not render page", "template", "table.html", "err", err)
 		}
 	}
 }
 
-func toFloat(str string) float64 {
-	str = strings.TrimSpace(str)
-	if len(str) == 0 {
-		return 0
+// toIntCalc evaluates the given expression. The string is returned
+// to be able to show the expression to the user again.
+func toIntCalc(str string, q calc.Quantity) (int, string, error) {
+	if strings.TrimSpace(str) == "" {
+		return 0, "", nil
 	}
-	f, err := strconv.ParseFloat(strings.ReplaceAll(str, ",", "."), 64)
+	res, err := calc.Int(str, q)
 	if err != nil {
-		return 0
+		return 0, str, fmt.Errorf("Fehler im Ausdruck '%s': %w", str, err)
 	}
-	return f
+	return res, str, nil
 }
 
-func toInt(str string) int {
-	str = strings.TrimSpace(str)
-	if len(str) == 0 {
-		return 0
+// toQuantity evaluates the expression describing the quantity of the given item.
+// The item is nil if a new item is created.
+func toQuantity(str string, data *item.ListData, i *item.Item) (float64, error) {
+	if strings.TrimSpace(str) == "" {
+		return 0, nil
 	}
-	f, err := strconv.Atoi(str)
+	res, err := calc.EvalVars(str, calc.Count, data.QuantityVars(i))
 	if err != nil {
-		return 0
+		return 0, fmt.Errorf("Fehler im Ausdruck '%s': %w", str, err)
 	}
-	return f
-}
-
-var simpleParser = funcGen.New[float64]().
-	AddConstant("pi", math.Pi).
-	AddSimpleOp("=", true, func(a, b float64) (float64, error) { return fromBool(a == b), nil }).
-	AddSimpleOp("<", false, func(a, b float64) (float64, error) { return fromBool(a < b), nil }).
-	AddSimpleOp(">", false, func(a, b float64) (float64, error) { return fromBool(a > b), nil }).
-	AddSimpleOp("+", true, func(a, b float64) (float64, error) { return a + b, nil }).
-	AddSimpleOp("-", false, func(a, b float64) (float64, error) { return a - b, nil }).
-	AddSimpleOp("*", true, func(a, b float64) (float64, error) { return a * b, nil }).
-	AddSimpleOp("/", false, func(a, b float64) (float64, error) { return a / b, nil }).
-	AddSimpleOp("^", false, func(a, b float64) (float64, error) { return math.Pow(a, b), nil }).
-	AddUnaryFunc("-", func(a float64) (float64, error) { return -a, nil }).
-	AddSimpleFunction("sin", math.Sin).
-	AddSimpleFunction("cos", math.Cos).
-	AddSimpleFunction("tan", math.Tan).
-	AddSimpleFunction("exp", math.Exp).
-	AddSimpleFunction("ln", math.Log).
-	AddSimpleFunction("sqrt", math.Sqrt).
-	AddSimpleFunction("sqr", func(x float64) float64 {
-		return x * x
-	}).
-	SetToBool(func(c float64) (bool, bool) { return c != 0, true }).
-	SetNumberParser(
-		parser2.NumberParserFunc[float64](
-			func(n string) (float64, error) {
-				return strconv.ParseFloat(n, 64)
-			},
-		),
-	)
-
-func fromBool(b bool) float64 {
-	if b {
-		return 1
-	} else {
-		return 0
-	}
-}
-
-func toIntCalc(str string) (int, string, error) {
-	if str == "" {
-		return 0, "", nil
-	}
-
-	f, err := simpleParser.Generate(str)
-	const format = "Fehler im Ausdruck '%s': %w"
-	if err != nil {
-		return 0, str, fmt.Errorf(format, str, err)
-	}
-	res, err := f.Eval()
-	if err != nil {
-		return 0, str, fmt.Errorf(format, str, err)
-	}
-	return int(res), str, nil
+	return res, nil
 }
 
 type addData struct {
 	Name       string
 	Unit       string
-	Quantity   float64
+	Quantity   string
 	Category   string
 	Weight     string
 	Volume     string
 	QHidden    bool
+	Tags       string
 	Categories []item.Category
 	Shops      []string
-	Error      error
-	Target     string
+	AllTags    []string
+	// TempId is the id of the temporary entry converted to an item
+	TempId int
+	Error  error
+	Target string
 }
 
 func AddHandler(w http.ResponseWriter, r *http.Request) {
 	if data, ok := r.Context().Value("data").(*item.ListData); ok {
 		target := ""
-		var itemName, itemUnit, category, shop string
-		var quantity float64 = 1
+		var itemName, itemUnit, category, shop, tags string
+		var tempId int
+		quantityStr := "1"
 		var volumeStr string
 		var weightStr string
 		var err error
@@ -242,69 +263,95 @@ func AddHandler(w http.ResponseWriter, r *http.Request) {
 			itemName = strings.TrimSpace(r.FormValue("name"))
 			itemUnit = strings.TrimSpace(r.FormValue("unit"))
 			shop = r.FormValue("shop")
+			tags = r.FormValue("tags")
+			p := formParams(r)
+			tempId = p.OptInt("tid", 0)
 			category = strings.TrimSpace(r.FormValue("category"))
-			quantity = toFloat(r.FormValue("quantity"))
-			var weight int
-			weight, weightStr, err = toIntCalc(r.FormValue("weight"))
+			quantityStr = r.FormValue("quantity")
+			var existing *item.Item
+			for _, e := range data.Items {
+				if e.Name == itemName && e.UnitSingular() == itemUnit {
+					existing = e
+					break
+				}
+			}
+			var quantity float64
+			err = p.Err()
+			if err == nil {
+				quantity, err = toQuantity(quantityStr, data, existing)
+			}
 			if err == nil {
-				var volume int
-				volume, volumeStr, err = toIntCalc(r.FormValue("volume"))
+				var weight int
+				weight, weightStr, err = toIntCalc(r.FormValue("weight"), calc.Mass)
 				if err == nil {
-					if len(itemName) > 0 {
-						found := false
-						for _, e := range data.Items {
-							if e.Name == itemName && e.UnitSingular() == itemUnit {
-								e.SetQuantity(quantity)
-								found = true
-								break
+					var volume int
+					volume, volumeStr, err = toIntCalc(r.FormValue("volume"), calc.Volume)
+					if err == nil {
+						if len(itemName) > 0 {
+							if existing != nil {
+								data.SetQuantity(existing.Id, quantity)
+							} else {
+								i := item.New(itemName, itemUnit, weight, weightStr, volume, volumeStr, item.Category(category), splitList(shop))
+								i.Tags = splitList(tags)
+								i.SetQuantity(quantity)
+								data.AddItem(i)
+							}
+							if tempId > 0 {
+								data.RemoveTemp(tempId)
 							}
-						}
-						if !found {
-							i := item.New(itemName, itemUnit, weight, weightStr, volume, volumeStr, item.Category(category), splitShop(shop))
-							i.SetQuantity(quantity)
-							data.AddItem(i)
-						}
 
-						t := r.FormValue("target")
-						if t == "all" {
-							http.Redirect(w, r, "/listAll", http.StatusFound)
-						} else {
-							http.Redirect(w, r, "/", http.StatusFound)
+							t := r.FormValue("target")
+							if t == "all" {
+								http.Redirect(w, r, "/listAll", http.StatusFound)
+							} else {
+								http.Redirect(w, r, "/", http.StatusFound)
+							}
+							return
 						}
-						return
 					}
 				}
 			}
+			badRequestView(w, p.Err())
 		} else {
 			category = r.URL.Query().Get("c")
 			if category == "" {
 				category = string(data.Categories()[0])
 			}
 			target = r.URL.Query().Get("t")
+			itemName = r.URL.Query().Get("name")
+			if id, err := strconv.Atoi(r.URL.Query().Get("tid")); err == nil {
+				if t := data.TempById(id); t != nil {
+					tempId = t.Id
+					itemName = t.Name
+				}
+			}
 		}
 		err = addTemp.Execute(w, addData{
 			Name:       itemName,
 			Unit:       itemUnit,
 			Category:   category,
-			Quantity:   quantity,
+			Quantity:   quantityStr,
 			Weight:     weightStr,
 			Volume:     volumeStr,
+			Tags:       tags,
 			QHidden:    false,
 			Categories: data.Categories(),
 			Shops:      data.Shops(),
+			AllTags:    data.Tags(),
+			TempId:     tempId,
 			Error:      err,
 			Target:     target,
 		})
 		if err != nil {
-			log.Println(err)
+			data.Logger().Error("could not render page", "template", "add.html", "err", err)
 		}
 		return
 	}
 }
 
-func splitShop(shop string) []string {
+func splitList(list string) []string {
 	var sl []string
-	for _, s := range strings.Split(shop, ",") {
+	for _, s := range strings.Split(list, ",") {
 		s = strings.TrimSpace(s)
 		if len(s) > 0 {
 			sl = append(sl, s)
@@ -315,28 +362,41 @@ func splitShop(shop string) []string {
 
 var listAllTemp = Templates.Lookup("listAll.html")
 
-func ListAllHandler(w http.ResponseWriter, r *http.Request) {
+type listAllData struct {
+	Data    *item.ListData
+	ShowAll bool
+	Tag     string
+	Tags    []string
+	Error   error
+}
 
-	type liData struct {
-		Data    *item.ListData
-		ShowAll bool
+func renderListAll(w http.ResponseWriter, r *http.Request, data *item.ListData, err error) {
+	query := r.URL.Query()
+	badRequestView(w, err)
+	err = listAllTemp.Execute(w, listAllData{
+		Data:    data,
+		ShowAll: query.Get("all") != "false",
+		Tag:     query.Get("tag"),
+		Tags:    data.Tags(),
+		Error:   err,
+	})
+	if err != nil {
+		data.Logger().Error("could not render page", "template", "listAll.html", "err", err)
 	}
+}
 
+func ListAllHandler(w http.ResponseWriter, r *http.Request) {
 	if data, ok := r.Context().Value("data").(*item.ListData); ok {
-		query := r.URL.Query()
-		idStr := query.Get("del")
-		if idStr != "" {
-			id, idErr := strconv.Atoi(idStr)
-			if idErr == nil || id >= 0 || id < len(data.Items) {
+		p := formParams(r)
+		if r.Method == http.MethodPost {
+			id := p.Id("del", data)
+			if p.Err() == nil {
 				data.DeleteItem(id)
+				http.Redirect(w, r, "/listAll", http.StatusFound)
+				return
 			}
 		}
-		showAll := query.Get("all") != "false"
-
-		err := listAllTemp.Execute(w, liData{Data: data, ShowAll: showAll})
-		if err != nil {
-			log.Println(err)
-		}
+		renderListAll(w, r, data, p.Err())
 	}
 }
 
@@ -344,22 +404,31 @@ var listAllRowTemp = Templates.Lookup("listAllRow.html")
 
 func ListAllModHandler(w http.ResponseWriter, r *http.Request) {
 	if data, ok := r.Context().Value("data").(*item.ListData); ok {
-		query := r.URL.Query()
-		idStr := query.Get("id")
-		if idStr != "" {
-			id := toInt(idStr)
-			if data.IdValid(id) {
-				data.ModQuantity(id, toFloat(query.Get("n")), true)
-				err := listAllRowTemp.Execute(w, data.ItemById(id))
-				if err != nil {
-					log.Println(err)
-				}
+		if r.Method != http.MethodPost {
+			methodNotAllowed(w)
+			return
+		}
+		p := formParams(r)
+		if p.Has("id") {
+			id := p.Id("id", data)
+			n := p.Float("n")
+			if p.Err() != nil {
+				writeBadRequest(w, p.Err())
+				return
+			}
+			data.ModQuantity(id, n, true)
+			err := listAllRowTemp.Execute(w, data.ItemById(id))
+			if err != nil {
+				data.Logger().Error("could not render page", "template", "listAllRow.html", "err", err)
 			}
 		} else {
-			cat := query.Get("cat")
-			if len(cat) > 3 {
-				data.SetCategoryString(cat)
+			cat := p.Str("cat")
+			if len(cat) <= 3 {
+				p.fail("cat", cat, "zu kurz")
+				writeBadRequest(w, p.Err())
+				return
 			}
+			data.SetCategoryString(cat)
 		}
 	}
 }
@@ -372,28 +441,32 @@ func EditHandler(w http.ResponseWriter, r *http.Request) {
 		var id int
 		var itemToEdit *item.Item
 		if r.Method == http.MethodPost {
-			idStr := r.FormValue("id")
-			var idErr error
-			id, idErr = strconv.Atoi(idStr)
-			if idErr != nil || !data.IdValid(id) {
-				http.Redirect(w, r, "/listAll", http.StatusFound)
-				return
-			}
-
+			p := formParams(r)
+			id = p.Id("id", data)
 			itemToEdit = &item.Item{
-				Name:     strings.TrimSpace(r.FormValue("name")),
-				Shops:    splitShop(r.FormValue("shop")),
-				UnitDef:  strings.TrimSpace(r.FormValue("unit")),
-				Category: item.Category(r.FormValue("category")),
+				Name:       strings.TrimSpace(r.FormValue("name")),
+				Shops:      splitList(r.FormValue("shop")),
+				Tags:       splitList(r.FormValue("tags")),
+				UnitDef:    strings.TrimSpace(r.FormValue("unit")),
+				Category:   item.Category(r.FormValue("category")),
+				TrackStock: r.FormValue("trackStock") != "",
+				Stock:      p.OptFloat("stock", 0),
+				MinStock:   p.OptFloat("minStock", 0),
+				ShelfLife:  p.OptInt("shelfLife", 0),
+				Recurrence: item.NewRecurrence(p.OptInt("recEvery", 0), item.RecurrenceUnit(p.OneOf("recUnit", "", "d", "w", "m")), p.OptFloat("recQuantity", 0)),
 			}
 
-			itemToEdit.Weight, itemToEdit.WeightStr, err = toIntCalc(r.FormValue("weight"))
+			itemToEdit.Weight, itemToEdit.WeightStr, err = toIntCalc(r.FormValue("weight"), calc.Mass)
 			if err == nil {
-				itemToEdit.Volume, itemToEdit.VolumeStr, err = toIntCalc(r.FormValue("volume"))
+				itemToEdit.Volume, itemToEdit.VolumeStr, err = toIntCalc(r.FormValue("volume"), calc.Volume)
 				if err == nil {
-					data.Replace(id, itemToEdit)
-					http.Redirect(w, r, "/listAll#q"+strconv.Itoa(id), http.StatusFound)
-					return
+					err = p.Err()
+					if err == nil {
+						data.Replace(id, itemToEdit)
+						http.Redirect(w, r, "/listAll#q"+strconv.Itoa(id), http.StatusFound)
+						return
+					}
+					badRequestView(w, err)
 				}
 			}
 		} else {
@@ -422,6 +495,7 @@ func EditHandler(w http.ResponseWriter, r *http.Request) {
 			Id         int
 			Categories []item.Category
 			Shops      []string
+			Tags       []string
 			Error      error
 			History    item.HistoryDescription
 		}{
@@ -429,13 +503,40 @@ func EditHandler(w http.ResponseWriter, r *http.Request) {
 			Id:         id,
 			Categories: data.Categories(),
 			Shops:      data.Shops(),
+			Tags:       data.Tags(),
 			Error:      err,
 			History:    itemToEdit.HistoryDescription(),
 		}
 
 		err = editTemp.Execute(w, d)
 		if err != nil {
-			log.Println(err)
+			data.Logger().Error("could not render page", "template", "edit.html", "err", err)
+		}
+	}
+}
+
+func SettingsHandler(w http.ResponseWriter, r *http.Request) {
+	if data, ok := r.Context().Value("data").(*item.ListData); ok {
+		if r.Method == http.MethodPost {
+			p := formParams(r)
+			persons := p.OptInt("persons", 1)
+			policy := item.AutoPaidPolicy{
+				Mode:      item.AutoPaidMode(p.OneOf("mode", "off", "idle", "at")),
+				IdleHours: p.OptInt("hours", 0),
+				At:        p.Str("at"),
+			}
+			if policy.Mode == item.AutoPaidAt {
+				if _, err := time.Parse("15:04", policy.At); err != nil {
+					p.fail("at", policy.At, "keine Uhrzeit")
+				}
+			}
+			if p.Err() != nil {
+				renderListAll(w, r, data, p.Err())
+				return
+			}
+			data.SetHouseholdSize(persons)
+			data.SetPolicy(policy)
 		}
+		http.Redirect(w, r, "/listAll#settings", http.StatusFound)
 	}
 }
@@ -0,0 +1,361 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/hneemann/shopping/calc"
+	"github.com/hneemann/shopping/item"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+func testList() *item.ListData {
+	ld := &item.ListData{CategoriesString: "Obst; Kühlregal"}
+	ld.AddItem(item.New("Äpfel", "kg", 0, "", 0, "", "Obst", nil))
+	ld.AddItem(item.New("Milch", "Packung", 1000, "", 1000, "", "Kühlregal", nil))
+	return ld
+}
+
+func call(h http.HandlerFunc, data any, method, target string, form url.Values) *httptest.ResponseRecorder {
+	var r *http.Request
+	if form != nil {
+		r = httptest.NewRequest(method, target, strings.NewReader(form.Encode()))
+		r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	} else {
+		r = httptest.NewRequest(method, target, nil)
+	}
+	r = r.WithContext(context.WithValue(r.Context(), "data", data))
+	w := httptest.NewRecorder()
+	h(w, r)
+	return w
+}
+
+func TestRestBadRequest(t *testing.T) {
+	tests := []struct {
+		handler http.HandlerFunc
+		target  string
+		param   string
+	}{
+		{TableHandler, "/table/?id=x&mode=car", "id"},
+		{TableHandler, "/table/?id=-1&mode=car", "id"},
+		{TableHandler, "/table/?id=17&mode=car", "id"},
+		{TableHandler, "/table/?id=1&mode=foo", "mode"},
+		{TableHandler, "/table/?id=1&mode=add&q=viel", "q"},
+		{TableHandler, "/table/?id=1&mode=add&q=NaN", "q"},
+		{TableHandler, "/table/?id=1&mode=add", "q"},
+		{TableHandler, "/table/?a=foo", "a"},
+		{TableHandler, "/table/?a=tt&tid=eins", "tid"},
+		{TableHandler, "/table/?a=tt&tid=3", "tid"},
+		{ListAllModHandler, "/listAllMod/?id=x&n=1", "id"},
+		{ListAllModHandler, "/listAllMod/?id=1&n=x", "n"},
+		{ListAllModHandler, "/listAllMod/?cat=a", "cat"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.target, func(t *testing.T) {
+			ld := testList()
+			w := call(tt.handler, ld, http.MethodPost, tt.target, nil)
+			assert.EqualValues(t, http.StatusBadRequest, w.Code)
+			assert.EqualValues(t, "application/json", w.Header().Get("Content-Type"))
+			var e inputError
+			assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &e))
+			assert.EqualValues(t, tt.param, e.Param)
+			assert.NotEmpty(t, e.Msg)
+			assert.EqualValues(t, 0, ld.ItemById(1).QuantityRequired)
+		})
+	}
+}
+
+func TestRestOk(t *testing.T) {
+	ld := testList()
+	w := call(TableHandler, ld, http.MethodPost, "/table/", url.Values{"id": {"1"}, "mode": {"add"}, "q": {"1,5"}})
+	assert.EqualValues(t, http.StatusOK, w.Code)
+	assert.EqualValues(t, 1.5, ld.ItemById(1).QuantityRequired)
+
+	w = call(TableHandler, ld, http.MethodPost, "/table/", url.Values{"id": {"1"}, "mode": {"set"}, "q": {"unbekannt"}})
+	assert.EqualValues(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), "class=\"error\"")
+	assert.EqualValues(t, 1.5, ld.ItemById(1).QuantityRequired)
+
+	w = call(ListAllModHandler, ld, http.MethodPost, "/listAllMod/", url.Values{"id": {"2"}, "n": {"2"}})
+	assert.EqualValues(t, http.StatusOK, w.Code)
+	assert.EqualValues(t, 2, ld.ItemById(2).QuantityRequired)
+}
+
+func TestViewBadRequest(t *testing.T) {
+	tests := []struct {
+		name    string
+		handler http.HandlerFunc
+		method  string
+		target  string
+		form    url.Values
+	}{
+		{"listAll del", ListAllHandler, http.MethodPost, "/listAll", url.Values{"del": {"x"}}},
+		{"listAll unknown", ListAllHandler, http.MethodPost, "/listAll", url.Values{"del": {"42"}}},
+		{"edit stock", EditHandler, http.MethodPost, "/edit/", url.Values{"id": {"1"}, "name": {"Äpfel"}, "stock": {"viele"}}},
+		{"edit unit", EditHandler, http.MethodPost, "/edit/", url.Values{"id": {"1"}, "name": {"Äpfel"}, "recUnit": {"y"}}},
+		{"edit id", EditHandler, http.MethodPost, "/edit/", url.Values{"id": {"x"}, "name": {"Äpfel"}}},
+		{"edit unknown id", EditHandler, http.MethodPost, "/edit/", url.Values{"id": {"42"}, "name": {"Äpfel"}}},
+		{"add tid", AddHandler, http.MethodPost, "/add/", url.Values{"name": {"Kerzen"}, "tid": {"x"}, "quantity": {"1"}}},
+		{"settings mode", SettingsHandler, http.MethodPost, "/settings", url.Values{"mode": {"never"}}},
+		{"settings at", SettingsHandler, http.MethodPost, "/settings", url.Values{"mode": {"at"}, "at": {"25:99"}}},
+		{"settings persons", SettingsHandler, http.MethodPost, "/settings", url.Values{"mode": {"off"}, "persons": {"-2"}}},
+		{"pantry id", PantryHandler, http.MethodPost, "/pantry", url.Values{"id": {"x"}, "a": {"empty"}}},
+		{"pantry q", PantryHandler, http.MethodPost, "/pantry", url.Values{"id": {"1"}, "a": {"set"}, "q": {"-1"}}},
+		{"useSoon days", UseSoonHandler, http.MethodGet, "/useSoon?d=bald", nil},
+		{"useSoon date", UseSoonHandler, http.MethodPost, "/useSoon", url.Values{"id": {"1"}, "n": {"0"}, "a": {"date"}, "bb": {"morgen"}}},
+		{"recipes del", RecipesHandler, http.MethodPost, "/recipes", url.Values{"del": {"x"}}},
+		{"mealPlan day", MealPlanHandler, http.MethodPost, "/mealPlan", url.Values{"a": {"plan"}, "day": {"Mo"}, "recipe": {"1"}}},
+		{"mealPlan rm", MealPlanHandler, http.MethodPost, "/mealPlan", url.Values{"a": {"rm"}, "id": {"7"}}},
+		{"template", ListTemplateHandler, http.MethodPost, "/template", url.Values{"a": {"foo"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ld := testList()
+			w := call(tt.handler, ld, tt.method, tt.target, tt.form)
+			assert.EqualValues(t, http.StatusBadRequest, w.Code)
+			assert.Contains(t, w.Body.String(), "class=\"error\"")
+			assert.Contains(t, w.Body.String(), "Ungültiger Wert")
+			assert.EqualValues(t, 2, len(ld.Items))
+		})
+	}
+}
+
+func TestAudit(t *testing.T) {
+	ld := testList()
+	ld.SetActor("anna", "Zuhause")
+	call(TableHandler, ld, http.MethodPost, "/table/", url.Values{"id": {"2"}, "mode": {"add"}, "q": {"1"}})
+	call(TableHandler, ld, http.MethodPost, "/table/", url.Values{"id": {"2"}, "mode": {"car"}})
+
+	w := call(AuditHandler, ld, http.MethodGet, "/audit", nil)
+	assert.EqualValues(t, http.StatusOK, w.Code)
+	body := w.Body.String()
+	car := strings.Index(body, "anna hat Milch in den Einkaufswagen gelegt")
+	mod := strings.Index(body, "anna hat die Menge von Milch um 1 erhöht")
+	assert.True(t, car >= 0 && mod > car, "newest entry first")
+}
+
+func TestFormatDate(t *testing.T) {
+	now := time.Now()
+	tests := []struct {
+		date time.Time
+		want string
+	}{
+		{now, "heute"},
+		{now.AddDate(0, 0, -1), "gestern"},
+		{now.AddDate(0, 0, -2), "vorgestern"},
+		{now.AddDate(0, 0, -5), "vor 5 Tagen"},
+		{now.AddDate(0, 0, 1), "morgen"},
+		{now.AddDate(0, 0, 2), "übermorgen"},
+		{now.AddDate(0, 0, 3), "in 3 Tagen"},
+		{time.Date(2020, 3, 7, 15, 30, 0, 0, time.Local), "07.03.2020"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.want, func(t *testing.T) {
+			assert.EqualValues(t, tt.want, formatDate(tt.date))
+		})
+	}
+}
+
+func TestNiceToStr(t *testing.T) {
+	tests := []struct {
+		v    float64
+		want string
+	}{
+		{0, "0"},
+		{2, "2"},
+		{-3, "-3"},
+		{1.5, "1.5"},
+		{0.1 + 0.2, "0.3"},
+		{1.25, "1.25"},
+		{1 / 3.0, "0.33"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.want, func(t *testing.T) {
+			assert.EqualValues(t, tt.want, niceToStr(tt.v))
+		})
+	}
+}
+
+func TestToIntCalc(t *testing.T) {
+	tests := []struct {
+		str     string
+		q       calc.Quantity
+		want    int
+		wantStr string
+		err     bool
+	}{
+		{"", calc.Mass, 0, "", false},
+		{"  ", calc.Mass, 0, "", false},
+		{"250", calc.Mass, 250, "250", false},
+		{"1,5kg", calc.Mass, 1500, "1,5kg", false},
+		{"6x0,33l", calc.Volume, 1980, "6x0,33l", false},
+		{"1kg", calc.Volume, 0, "1kg", true},
+		{"viel", calc.Mass, 0, "viel", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.str, func(t *testing.T) {
+			got, str, err := toIntCalc(tt.str, tt.q)
+			if tt.err {
+				assert.ErrorContains(t, err, "Fehler im Ausdruck")
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.EqualValues(t, tt.want, got)
+			assert.EqualValues(t, tt.wantStr, str)
+		})
+	}
+}
+
+func TestHandlers(t *testing.T) {
+	tests := []struct {
+		name     string
+		handler  http.HandlerFunc
+		method   string
+		target   string
+		form     url.Values
+		location string
+		check    func(t *testing.T, ld *item.ListData, body string)
+	}{
+		{"main", MainHandler, http.MethodGet, "/", nil, "", func(t *testing.T, ld *item.ListData, body string) {
+			assert.Contains(t, body, "Äpfel")
+		}},
+		{"table na", TableHandler, http.MethodPost, "/table/", url.Values{"id": {"1"}, "mode": {"na"}}, "", func(t *testing.T, ld *item.ListData, body string) {
+			assert.True(t, ld.ItemById(1).IsNotAvailable)
+		}},
+		{"table car", TableHandler, http.MethodPost, "/table/", url.Values{"id": {"2"}, "mode": {"car"}}, "", func(t *testing.T, ld *item.ListData, body string) {
+			assert.True(t, ld.ItemById(2).IsInCar)
+		}},
+		{"table del", TableHandler, http.MethodPost, "/table/", url.Values{"id": {"2"}, "mode": {"del"}}, "", func(t *testing.T, ld *item.ListData, body string) {
+			assert.EqualValues(t, 0, ld.ItemById(2).QuantityRequired)
+		}},
+		{"table set", TableHandler, http.MethodPost, "/table/", url.Values{"id": {"2"}, "mode": {"set"}, "q": {"2*3"}}, "", func(t *testing.T, ld *item.ListData, body string) {
+			assert.EqualValues(t, 6, ld.ItemById(2).QuantityRequired)
+		}},
+		{"table paid", TableHandler, http.MethodPost, "/table/", url.Values{"a": {"paid"}}, "", func(t *testing.T, ld *item.ListData, body string) {
+			assert.EqualValues(t, 0, ld.ItemById(1).QuantityRequired)
+			assert.EqualValues(t, 1, ld.ItemById(2).QuantityRequired)
+		}},
+		{"table temp", TableHandler, http.MethodPost, "/table/", url.Values{"a": {"at"}, "n": {"Kerzen"}}, "", func(t *testing.T, ld *item.ListData, body string) {
+			assert.EqualValues(t, 2, len(ld.TempItems))
+			assert.Contains(t, body, "Kerzen")
+		}},
+		{"table temp match", TableHandler, http.MethodPost, "/table/", url.Values{"a": {"at"}, "n": {"Milch"}}, "", func(t *testing.T, ld *item.ListData, body string) {
+			assert.EqualValues(t, 1, len(ld.TempItems))
+		}},
+		{"table quick add", TableHandler, http.MethodPost, "/table/", url.Values{"a": {"qa"}, "n": {"2 Milch"}}, "", func(t *testing.T, ld *item.ListData, body string) {
+			assert.EqualValues(t, 3, ld.ItemById(2).QuantityRequired)
+		}},
+		{"table quick add unit", TableHandler, http.MethodPost, "/table/", url.Values{"a": {"qa"}, "n": {"500 g Milch"}}, "", func(t *testing.T, ld *item.ListData, body string) {
+			assert.EqualValues(t, 1, ld.ItemById(2).QuantityRequired)
+			assert.Contains(t, body, "class=\"error\"")
+			assert.Contains(t, body, "value=\"500 g Milch\"")
+		}},
+		{"table toggle temp", TableHandler, http.MethodPost, "/table/", url.Values{"a": {"tt"}, "tid": {"1"}}, "", func(t *testing.T, ld *item.ListData, body string) {
+			assert.True(t, ld.TempItems[0].IsInCar)
+		}},
+		{"table get", TableHandler, http.MethodGet, "/table/?id=1&mode=car", nil, "", nil},
+		{"add form", AddHandler, http.MethodGet, "/add/?c=Kühlregal&name=Quark", nil, "", func(t *testing.T, ld *item.ListData, body string) {
+			assert.Contains(t, body, "value=\"Quark\"")
+			assert.NotContains(t, body, "name=\"tid\"")
+		}},
+		{"add form temp", AddHandler, http.MethodGet, "/add/?tid=1", nil, "", func(t *testing.T, ld *item.ListData, body string) {
+			assert.Contains(t, body, "value=\"Kerzen\"")
+			assert.Contains(t, body, "<input type=\"hidden\" name=\"tid\" value=\"1\"/>")
+		}},
+		{"add new", AddHandler, http.MethodPost, "/add/", url.Values{"name": {"Quark"}, "unit": {"Becher"}, "category": {"Kühlregal"}, "quantity": {"2"}, "weight": {"500g"}, "shop": {"Markt, Bäcker"}, "tags": {"bio"}, "target": {"all"}}, "/listAll", func(t *testing.T, ld *item.ListData, body string) {
+			q := ld.ItemById(3)
+			assert.EqualValues(t, "Quark", q.Name)
+			assert.EqualValues(t, 2, q.QuantityRequired)
+			assert.EqualValues(t, 500, q.Weight)
+			assert.EqualValues(t, []string{"Markt", "Bäcker"}, q.Shops)
+			assert.EqualValues(t, []string{"bio"}, q.Tags)
+		}},
+		{"add existing", AddHandler, http.MethodPost, "/add/", url.Values{"name": {"Milch"}, "unit": {"Packung"}, "quantity": {"3"}}, "/", func(t *testing.T, ld *item.ListData, body string) {
+			assert.EqualValues(t, 2, len(ld.Items))
+			assert.EqualValues(t, 3, ld.ItemById(2).QuantityRequired)
+		}},
+		{"add temp", AddHandler, http.MethodPost, "/add/", url.Values{"name": {"Kerzen"}, "tid": {"1"}, "quantity": {"1"}}, "/", func(t *testing.T, ld *item.ListData, body string) {
+			assert.EqualValues(t, 0, len(ld.TempItems))
+			assert.EqualValues(t, 3, len(ld.Items))
+		}},
+		{"add temp unknown id", AddHandler, http.MethodPost, "/add/", url.Values{"name": {"Kerzen"}, "tid": {"7"}, "quantity": {"1"}}, "/", func(t *testing.T, ld *item.ListData, body string) {
+			assert.EqualValues(t, 1, len(ld.TempItems))
+			assert.EqualValues(t, 3, len(ld.Items))
+		}},
+		{"add weight error", AddHandler, http.MethodPost, "/add/", url.Values{"name": {"Quark"}, "weight": {"1l"}}, "", func(t *testing.T, ld *item.ListData, body string) {
+			assert.EqualValues(t, 2, len(ld.Items))
+			assert.Contains(t, body, "Fehler im Ausdruck")
+		}},
+		{"listAll", ListAllHandler, http.MethodGet, "/listAll", nil, "", func(t *testing.T, ld *item.ListData, body string) {
+			assert.Contains(t, body, "Milch")
+		}},
+		{"listAll del", ListAllHandler, http.MethodPost, "/listAll", url.Values{"del": {"1"}}, "/listAll", func(t *testing.T, ld *item.ListData, body string) {
+			assert.Nil(t, ld.ItemById(1))
+		}},
+		{"listAllMod", ListAllModHandler, http.MethodPost, "/listAllMod/", url.Values{"id": {"2"}, "n": {"-1"}}, "", func(t *testing.T, ld *item.ListData, body string) {
+			assert.EqualValues(t, 0, ld.ItemById(2).QuantityRequired)
+			assert.Contains(t, body, "Milch")
+		}},
+		{"listAllMod cat", ListAllModHandler, http.MethodPost, "/listAllMod/", url.Values{"cat": {"Kühlregal; Obst"}}, "", func(t *testing.T, ld *item.ListData, body string) {
+			assert.EqualValues(t, "Kühlregal; Obst", ld.CategoriesString)
+		}},
+		{"edit form", EditHandler, http.MethodGet, "/edit/?item=2", nil, "", func(t *testing.T, ld *item.ListData, body string) {
+			assert.Contains(t, body, "value=\"Milch\"")
+		}},
+		{"edit unknown", EditHandler, http.MethodGet, "/edit/?item=42", nil, "/listAll", nil},
+		{"edit", EditHandler, http.MethodPost, "/edit/", url.Values{"id": {"2"}, "name": {"Vollmilch"}, "unit": {"Flasche"}, "category": {"Kühlregal"}, "volume": {"1,5l"}, "trackStock": {"on"}, "minStock": {"2"}}, "/listAll#q2", func(t *testing.T, ld *item.ListData, body string) {
+			m := ld.ItemById(2)
+			assert.EqualValues(t, "Vollmilch", m.Name)
+			assert.EqualValues(t, 1500, m.Volume)
+			assert.True(t, m.TrackStock)
+			assert.EqualValues(t, 2, m.MinStock)
+		}},
+		{"edit volume error", EditHandler, http.MethodPost, "/edit/", url.Values{"id": {"2"}, "name": {"Vollmilch"}, "volume": {"1kg"}}, "", func(t *testing.T, ld *item.ListData, body string) {
+			assert.EqualValues(t, "Milch", ld.ItemById(2).Name)
+			assert.Contains(t, body, "Fehler im Ausdruck")
+		}},
+		{"settings", SettingsHandler, http.MethodPost, "/settings", url.Values{"mode": {"at"}, "at": {"20:00"}, "persons": {"3"}}, "/listAll#settings", func(t *testing.T, ld *item.ListData, body string) {
+			assert.EqualValues(t, item.AutoPaidAt, ld.Policy().Mode)
+			assert.EqualValues(t, "20:00", ld.Policy().At)
+			assert.EqualValues(t, 3, ld.Persons)
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ld := testList()
+			ld.SetQuantity(1, 2)
+			ld.SetQuantity(2, 1)
+			ld.ToggleInCar(1)
+			ld.AddTemp("Kerzen", true)
+			w := call(tt.handler, ld, tt.method, tt.target, tt.form)
+			switch {
+			case tt.location != "":
+				assert.EqualValues(t, http.StatusFound, w.Code)
+				assert.EqualValues(t, tt.location, w.Header().Get("Location"))
+			case tt.check == nil:
+				assert.EqualValues(t, http.StatusMethodNotAllowed, w.Code)
+			default:
+				assert.EqualValues(t, http.StatusOK, w.Code)
+			}
+			if tt.check != nil {
+				tt.check(t, ld, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestTableNormalizedCategory(t *testing.T) {
+	ld := testList()
+	ld.AddItem(item.New("Joghurt", "Becher", 150, "", 0, "", "Kühlregal>Milch", nil))
+	ld.SetQuantity(3, 1)
+	w := call(TableHandler, ld, http.MethodGet, "/table/", nil)
+	assert.EqualValues(t, http.StatusOK, w.Code)
+	body := w.Body.String()
+	assert.Contains(t, body, `data-head="Kühlregal &gt; Milch"`)
+	assert.Contains(t, body, `<tr data-cat="Kühlregal &gt; Milch">`)
+}
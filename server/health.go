@@ -0,0 +1,52 @@
+package server
+
+import (
+	"log/slog"
+	"net/http"
+	"sync/atomic"
+)
+
+// Health provides the liveness and readiness probes used by a container orchestrator
+type Health struct {
+	shuttingDown atomic.Bool
+	check        func() error
+}
+
+// NewHealth creates the probes. The check function is called by the
+// readiness probe and returns an error if the server can not serve requests.
+func NewHealth(check func() error) *Health {
+	return &Health{check: check}
+}
+
+// ShuttingDown marks the server as not ready, so that no new requests are sent to it
+func (h *Health) ShuttingDown() {
+	h.shuttingDown.Store(true)
+}
+
+// Healthz reports that the process is alive
+func (h *Health) Healthz(w http.ResponseWriter, _ *http.Request) {
+	writeProbe(w, http.StatusOK, "ok")
+}
+
+// Readyz reports whether the server is able to serve requests
+func (h *Health) Readyz(w http.ResponseWriter, _ *http.Request) {
+	if h.shuttingDown.Load() {
+		writeProbe(w, http.StatusServiceUnavailable, "shutting down")
+		return
+	}
+	if h.check != nil {
+		if err := h.check(); err != nil {
+			slog.Warn("not ready", "err", err)
+			writeProbe(w, http.StatusServiceUnavailable, "not ready")
+			return
+		}
+	}
+	writeProbe(w, http.StatusOK, "ok")
+}
+
+func writeProbe(w http.ResponseWriter, status int, msg string) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	_, _ = w.Write([]byte(msg + "\n"))
+}
@@ -0,0 +1,34 @@
+package server
+
+import (
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func probe(h http.HandlerFunc) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	h(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	return w
+}
+
+func TestHealth(t *testing.T) {
+	var checkErr error
+	h := NewHealth(func() error { return checkErr })
+
+	assert.EqualValues(t, http.StatusOK, probe(h.Healthz).Code)
+	assert.EqualValues(t, http.StatusOK, probe(h.Readyz).Code)
+
+	checkErr = errors.New("data folder missing")
+	w := probe(h.Readyz)
+	assert.EqualValues(t, http.StatusServiceUnavailable, w.Code)
+	assert.EqualValues(t, "not ready\n", w.Body.String(), "the error is not exposed")
+	assert.EqualValues(t, http.StatusOK, probe(h.Healthz).Code)
+
+	checkErr = nil
+	h.ShuttingDown()
+	assert.EqualValues(t, http.StatusServiceUnavailable, probe(h.Readyz).Code)
+	assert.EqualValues(t, http.StatusOK, probe(h.Healthz).Code)
+}
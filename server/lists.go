@@ -0,0 +1,118 @@
+package server
+
+import (
+	"context"
+	"github.com/hneemann/shopping/item"
+	"log/slog"
+	"net/http"
+	"net/url"
+)
+
+const listCookie = "list"
+
+// selectedList returns the name of the list selected on the device sending
+// the request. The selection is stored in a cookie, so that each device can
+// show another list.
+func selectedList(r *http.Request) string {
+	if c, err := r.Cookie(listCookie); err == nil {
+		if name, err := url.QueryUnescape(c.Value); err == nil {
+			return name
+		}
+	}
+	return ""
+}
+
+func setSelectedList(w http.ResponseWriter, name string) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     listCookie,
+		Value:    url.QueryEscape(name),
+		Path:     "/",
+		MaxAge:   365 * 24 * 60 * 60,
+		Secure:   true,
+		HttpOnly: true,
+		SameSite: http.SameSiteStrictMode,
+	})
+}
+
+// SelectList passes the list selected on the device to the parent handler.
+// The account is locked while the parent handler runs. After the parent
+// handler has returned, the catalog of the modified list is copied to all
+// lists sharing it.
+func SelectList(parent http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if account, ok := r.Context().Value("data").(*item.Account); ok {
+			account.Lock()
+			defer account.Unlock()
+			cur := account.Named(selectedList(r))
+			cur.List.SetActor(account.User(), cur.Name)
+			ctx := context.WithValue(r.Context(), "data", cur.List)
+			ctx = context.WithValue(ctx, "account", account)
+			ctx = context.WithValue(ctx, "list", cur.Name)
+			parent(w, r.WithContext(ctx))
+			account.SyncCatalog(cur)
+		}
+	}
+}
+
+func accountOf(r *http.Request) *item.Account {
+	if account, ok := r.Context().Value("account").(*item.Account); ok {
+		return account
+	}
+	return nil
+}
+
+// listNameOf returns the name of the list passed to the handler
+func listNameOf(r *http.Request) string {
+	if name, ok := r.Context().Value("list").(string); ok {
+		return name
+	}
+	return ""
+}
+
+var listsTemp = Templates.Lookup("lists.html")
+
+type listsData struct {
+	Account  *item.Account
+	Selected string
+	Error    error
+}
+
+func ListsHandler(w http.ResponseWriter, r *http.Request) {
+	if account, ok := r.Context().Value("data").(*item.Account); ok {
+		account.Lock()
+		defer account.Unlock()
+		p := formParams(r)
+		cur := account.Named(selectedList(r))
+		var err error
+		if r.Method == http.MethodPost {
+			switch p.OneOf("a", "sel", "create", "del") {
+			case "sel":
+				name := p.List("name", account)
+				if p.Err() == nil {
+					setSelectedList(w, name)
+					http.Redirect(w, r, "/", http.StatusFound)
+					return
+				}
+			case "create":
+				err = account.AddList(p.Str("name"), p.Str("shared") == "on", cur)
+			case "del":
+				name := p.List("name", account)
+				if p.Err() == nil {
+					account.DeleteList(name)
+				}
+			}
+		}
+		if p.Err() != nil {
+			err = p.Err()
+			badRequestView(w, err)
+		}
+		err = listsTemp.Execute(w, listsData{
+			Account:  account,
+			Selected: account.Named(cur.Name).Name,
+			Error:    err,
+		})
+		if err != nil {
+			slog.Error("could not render page", "template", "lists.html", "user", account.User(), "err", err)
+		}
+	}
+}
@@ -0,0 +1,119 @@
+package server
+
+import (
+	"github.com/hneemann/shopping/item"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/url"
+	"testing"
+)
+
+func testAccount() *item.Account {
+	a := &item.Account{}
+	a.Lists = []*item.NamedList{{Name: "Zuhause", List: testList()}}
+	return a
+}
+
+// withList sends the cookie selecting the given list with each request
+func withList(name string, h http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		r.AddCookie(&http.Cookie{Name: listCookie, Value: url.QueryEscape(name)})
+		h(w, r)
+	}
+}
+
+func TestListsHandler(t *testing.T) {
+	a := testAccount()
+	w := call(ListsHandler, a, http.MethodPost, "/lists", url.Values{"a": {"create"}, "name": {"Büro"}, "shared": {"on"}})
+	assert.EqualValues(t, http.StatusOK, w.Code)
+	assert.EqualValues(t, 2, len(a.Lists))
+	assert.True(t, a.Lists[1].Shared)
+	assert.Contains(t, w.Body.String(), "Büro")
+
+	w = call(ListsHandler, a, http.MethodPost, "/lists", url.Values{"a": {"create"}, "name": {"Büro"}})
+	assert.EqualValues(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), "eine Liste mit diesem Namen gibt es schon")
+	assert.EqualValues(t, 2, len(a.Lists))
+
+	w = call(ListsHandler, a, http.MethodPost, "/lists", url.Values{"a": {"sel"}, "name": {"Büro"}})
+	assert.EqualValues(t, http.StatusFound, w.Code)
+	assert.EqualValues(t, "/", w.Header().Get("Location"))
+	cookies := w.Result().Cookies()
+	if assert.Len(t, cookies, 1) {
+		assert.EqualValues(t, listCookie, cookies[0].Name)
+		assert.EqualValues(t, "B%C3%BCro", cookies[0].Value)
+	}
+
+	w = call(withList("Büro", ListsHandler), a, http.MethodGet, "/lists", nil)
+	assert.Contains(t, w.Body.String(), "<b>Büro</b>")
+
+	w = call(withList("Büro", ListsHandler), a, http.MethodPost, "/lists", url.Values{"a": {"del"}, "name": {"Zuhause"}})
+	assert.EqualValues(t, http.StatusOK, w.Code)
+	assert.EqualValues(t, 1, len(a.Lists))
+	assert.Contains(t, w.Body.String(), "<b>Büro</b>")
+
+	w = call(ListsHandler, a, http.MethodPost, "/lists", url.Values{"a": {"sel"}, "name": {"Zuhause"}})
+	assert.EqualValues(t, http.StatusBadRequest, w.Code)
+	w = call(ListsHandler, a, http.MethodPost, "/lists", url.Values{"a": {"sel"}})
+	assert.EqualValues(t, http.StatusBadRequest, w.Code)
+}
+
+func TestListsHandler_StaleDelete(t *testing.T) {
+	a := testAccount()
+	assert.NoError(t, a.AddList("Büro", false, a.Named("")))
+	assert.NoError(t, a.AddList("Ferienwohnung", false, a.Named("")))
+
+	// a stale page still shows the list Büro which was deleted in another tab
+	call(ListsHandler, a, http.MethodPost, "/lists", url.Values{"a": {"del"}, "name": {"Büro"}})
+	w := call(ListsHandler, a, http.MethodPost, "/lists", url.Values{"a": {"del"}, "name": {"Büro"}})
+	assert.EqualValues(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "unbekannte Liste")
+	assert.NotNil(t, a.ListByName("Zuhause"))
+	assert.NotNil(t, a.ListByName("Ferienwohnung"))
+	assert.Nil(t, a.ListByName("Büro"))
+}
+
+func TestSelectList(t *testing.T) {
+	a := testAccount()
+	assert.NoError(t, a.AddList("Büro", true, a.Named("")))
+	a.SetUser("anna")
+
+	w := call(withList("Büro", SelectList(AddHandler)), a, http.MethodPost, "/add/", url.Values{"name": {"Kaffee"}, "unit": {"Packung"}, "quantity": {"1"}})
+	assert.EqualValues(t, http.StatusFound, w.Code)
+
+	office := a.Named("Büro").List
+	assert.EqualValues(t, "Kaffee", office.ItemById(3).Name)
+	assert.EqualValues(t, 1, office.ItemById(3).QuantityRequired)
+	assert.EqualValues(t, "anna", office.Audit[len(office.Audit)-1].User)
+	home := a.Named("Zuhause").List
+	if assert.NotNil(t, home.ItemById(3), "catalog is shared") {
+		assert.EqualValues(t, 0, home.ItemById(3).QuantityRequired)
+	}
+}
+
+func TestSelectList_PerDevice(t *testing.T) {
+	a := testAccount()
+	assert.NoError(t, a.AddList("Büro", false, a.Named("")))
+
+	phone := withList("Büro", SelectList(TableHandler))
+	tablet := SelectList(TableHandler)
+	call(phone, a, http.MethodPost, "/table/", url.Values{"a": {"at"}, "n": {"Toner"}, "f": {"1"}})
+	call(tablet, a, http.MethodPost, "/table/", url.Values{"a": {"at"}, "n": {"Kerzen"}, "f": {"1"}})
+
+	assert.EqualValues(t, "Toner", a.Named("Büro").List.TempItems[0].Name)
+	assert.EqualValues(t, "Kerzen", a.Named("Zuhause").List.TempItems[0].Name)
+	assert.Contains(t, call(phone, a, http.MethodGet, "/table/", nil).Body.String(), "<option value=\"Büro\" selected>Büro</option>")
+	assert.Contains(t, call(tablet, a, http.MethodGet, "/table/", nil).Body.String(), "<option value=\"Zuhause\" selected>Zuhause</option>")
+}
+
+func TestListsHandler_SharedFromUnshared(t *testing.T) {
+	a := testAccount()
+	assert.NoError(t, a.AddList("Büro", true, a.Named("")))
+	assert.NoError(t, a.AddList("Keller", false, a.Named("")))
+
+	w := call(withList("Keller", ListsHandler), a, http.MethodPost, "/lists", url.Values{"a": {"create"}, "name": {"Garage"}, "shared": {"on"}})
+	assert.EqualValues(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), "es gibt schon einen gemeinsamen Katalog")
+	assert.Nil(t, a.ListByName("Garage"))
+	assert.False(t, a.ListByName("Keller").Shared)
+}
@@ -0,0 +1,34 @@
+package server
+
+import (
+	"github.com/hneemann/shopping/item"
+	"net/http"
+)
+
+func ListTemplateHandler(w http.ResponseWriter, r *http.Request) {
+	if data, ok := r.Context().Value("data").(*item.ListData); ok {
+		if r.Method == http.MethodPost {
+			p := formParams(r)
+			name := p.Str("name")
+			switch p.OneOf("a", "create", "del", "add", "max") {
+			case "create":
+				data.CreateTemplate(name)
+			case "del":
+				data.DeleteTemplate(name)
+			case "add":
+				data.ApplyTemplate(name, item.MergeAdd)
+				http.Redirect(w, r, "/", http.StatusFound)
+				return
+			case "max":
+				data.ApplyTemplate(name, item.MergeMax)
+				http.Redirect(w, r, "/", http.StatusFound)
+				return
+			}
+			if p.Err() != nil {
+				renderListAll(w, r, data, p.Err())
+				return
+			}
+		}
+		http.Redirect(w, r, "/listAll#templates", http.StatusFound)
+	}
+}
@@ -0,0 +1,34 @@
+package server
+
+import (
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/url"
+	"testing"
+)
+
+func TestListTemplateHandler(t *testing.T) {
+	ld := testList()
+	ld.SetQuantity(2, 2)
+	ld.AddTemp("Kerzen", true)
+
+	w := call(ListTemplateHandler, ld, http.MethodPost, "/template", url.Values{"a": {"create"}, "name": {"Wochenende"}})
+	assert.EqualValues(t, http.StatusFound, w.Code)
+	assert.EqualValues(t, "/listAll#templates", w.Header().Get("Location"))
+	assert.NotNil(t, ld.TemplateByName("Wochenende"))
+
+	ld.SetQuantity(2, 1)
+	w = call(ListTemplateHandler, ld, http.MethodPost, "/template", url.Values{"a": {"max"}, "name": {"Wochenende"}})
+	assert.EqualValues(t, http.StatusFound, w.Code)
+	assert.EqualValues(t, "/", w.Header().Get("Location"))
+	assert.EqualValues(t, 2, ld.ItemById(2).QuantityRequired)
+	assert.EqualValues(t, 1, len(ld.TempItems))
+
+	w = call(ListTemplateHandler, ld, http.MethodPost, "/template", url.Values{"a": {"add"}, "name": {"Wochenende"}})
+	assert.EqualValues(t, http.StatusFound, w.Code)
+	assert.EqualValues(t, 4, ld.ItemById(2).QuantityRequired)
+
+	w = call(ListTemplateHandler, ld, http.MethodPost, "/template", url.Values{"a": {"del"}, "name": {"Wochenende"}})
+	assert.EqualValues(t, http.StatusFound, w.Code)
+	assert.Nil(t, ld.TemplateByName("Wochenende"))
+}
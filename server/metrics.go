@@ -0,0 +1,48 @@
+package server
+
+import (
+	"github.com/hneemann/shopping/metrics"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+var (
+	requestCount = metrics.NewCounter("shopping_http_requests_total",
+		"Number of http requests by handler and status code.", "handler", "code")
+	requestDuration = metrics.NewHistogram("shopping_http_request_duration_seconds",
+		"Duration of the http requests by handler.", metrics.DefaultBuckets, "handler")
+)
+
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (s *statusRecorder) WriteHeader(status int) {
+	if s.status == 0 {
+		s.status = status
+	}
+	s.ResponseWriter.WriteHeader(status)
+}
+
+func (s *statusRecorder) Write(b []byte) (int, error) {
+	if s.status == 0 {
+		s.status = http.StatusOK
+	}
+	return s.ResponseWriter.Write(b)
+}
+
+// Instrument counts the requests handled by the parent handler and measures their duration
+func Instrument(name string, parent http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		sr := &statusRecorder{ResponseWriter: w}
+		parent(sr, r)
+		if sr.status == 0 {
+			sr.status = http.StatusOK
+		}
+		requestDuration.Since(start, name)
+		requestCount.Inc(name, strconv.Itoa(sr.status))
+	}
+}
@@ -0,0 +1,23 @@
+package server
+
+import (
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/url"
+	"testing"
+)
+
+func TestInstrument(t *testing.T) {
+	ok := requestCount.Value("TableHandler", "200")
+	bad := requestCount.Value("TableHandler", "400")
+	count := requestDuration.Count("TableHandler")
+
+	h := Instrument("TableHandler", TableHandler)
+	ld := testList()
+	call(h, ld, http.MethodPost, "/table/", url.Values{"id": {"1"}, "mode": {"car"}})
+	call(h, ld, http.MethodPost, "/table/", url.Values{"id": {"x"}, "mode": {"car"}})
+
+	assert.EqualValues(t, ok+1, requestCount.Value("TableHandler", "200"))
+	assert.EqualValues(t, bad+1, requestCount.Value("TableHandler", "400"))
+	assert.EqualValues(t, count+2, requestDuration.Count("TableHandler"))
+}
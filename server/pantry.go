@@ -0,0 +1,100 @@
+package server
+
+import (
+	"github.com/hneemann/shopping/item"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+var pantryTemp = Templates.Lookup("pantry.html")
+
+func PantryHandler(w http.ResponseWriter, r *http.Request) {
+	if data, ok := r.Context().Value("data").(*item.ListData); ok {
+		p := formParams(r)
+		if r.Method == http.MethodPost {
+			id := p.Id("id", data)
+			a := p.OneOf("a", "consume", "empty", "set")
+			var q float64
+			if a == "set" {
+				q = p.OptFloat("q", 0)
+			}
+			if p.Err() == nil {
+				switch a {
+				case "consume":
+					data.Consume(id, data.ItemById(id).Increment())
+				case "empty":
+					data.SetStock(id, 0)
+				case "set":
+					data.SetStock(id, q)
+				}
+				http.Redirect(w, r, "/pantry#p"+strconv.Itoa(id), http.StatusFound)
+				return
+			}
+		}
+		badRequestView(w, p.Err())
+
+		var d = struct {
+			*item.ListData
+			Error error
+		}{
+			ListData: data,
+			Error:    p.Err(),
+		}
+		err := pantryTemp.Execute(w, d)
+		if err != nil {
+			data.Logger().Error("could not render page", "template", "pantry.html", "err", err)
+		}
+	}
+}
+
+const defaultUseSoonDays = 3
+
+var useSoonTemp = Templates.Lookup("useSoon.html")
+
+func UseSoonHandler(w http.ResponseWriter, r *http.Request) {
+	if data, ok := r.Context().Value("data").(*item.ListData); ok {
+		p := formParams(r)
+		days := p.OptInt("d", defaultUseSoonDays)
+		if r.Method == http.MethodPost && p.Err() == nil {
+			id := p.Id("id", data)
+			n := p.Int("n")
+			a := p.OneOf("a", "used", "waste", "date")
+			var bb time.Time
+			if a == "date" {
+				var err error
+				bb, err = time.ParseInLocation("2006-01-02", p.Str("bb"), time.Local)
+				if err != nil {
+					p.fail("bb", p.Str("bb"), "kein Datum")
+				}
+			}
+			if p.Err() == nil {
+				switch a {
+				case "used":
+					data.ConsumeBatch(id, n)
+				case "waste":
+					data.WasteBatch(id, n)
+				case "date":
+					data.SetBestBefore(id, n, bb)
+				}
+				http.Redirect(w, r, "/useSoon?d="+strconv.Itoa(days), http.StatusFound)
+				return
+			}
+		}
+		badRequestView(w, p.Err())
+
+		var d = struct {
+			Days    int
+			Batches []item.ExpiringBatch
+			Error   error
+		}{
+			Days:    days,
+			Batches: data.UseSoon(days),
+			Error:   p.Err(),
+		}
+		err := useSoonTemp.Execute(w, d)
+		if err != nil {
+			data.Logger().Error("could not render page", "template", "useSoon.html", "err", err)
+		}
+	}
+}
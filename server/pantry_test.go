@@ -0,0 +1,93 @@
+package server
+
+import (
+	"github.com/hneemann/shopping/item"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+)
+
+func pantryList() *item.ListData {
+	ld := testList()
+	milk := ld.ItemById(2)
+	milk.TrackStock = true
+	milk.Stock = 3
+	milk.ShelfLife = 7
+	tomorrow := time.Now().AddDate(0, 0, 1)
+	milk.Batches = []item.Batch{{Quantity: 1, BestBefore: tomorrow}, {Quantity: 2, BestBefore: tomorrow.AddDate(0, 0, 5)}}
+	return ld
+}
+
+func TestPantryHandler(t *testing.T) {
+	tests := []struct {
+		name     string
+		method   string
+		form     url.Values
+		location string
+		stock    float64
+	}{
+		{"show", http.MethodGet, nil, "", 3},
+		{"consume", http.MethodPost, url.Values{"id": {"2"}, "a": {"consume"}}, "/pantry#p2", 2},
+		{"empty", http.MethodPost, url.Values{"id": {"2"}, "a": {"empty"}}, "/pantry#p2", 0},
+		{"set", http.MethodPost, url.Values{"id": {"2"}, "a": {"set"}, "q": {"1,5"}}, "/pantry#p2", 1.5},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ld := pantryList()
+			w := call(PantryHandler, ld, tt.method, "/pantry", tt.form)
+			if tt.location == "" {
+				assert.EqualValues(t, http.StatusOK, w.Code)
+				assert.Contains(t, w.Body.String(), "Milch")
+			} else {
+				assert.EqualValues(t, http.StatusFound, w.Code)
+				assert.EqualValues(t, tt.location, w.Header().Get("Location"))
+			}
+			assert.EqualValues(t, tt.stock, ld.ItemById(2).Stock)
+		})
+	}
+}
+
+func TestUseSoonHandler(t *testing.T) {
+	nextWeek := time.Now().AddDate(0, 0, 7).Format("2006-01-02")
+	tests := []struct {
+		name     string
+		method   string
+		target   string
+		form     url.Values
+		location string
+		batches  int
+		stock    float64
+	}{
+		{"show", http.MethodGet, "/useSoon?d=3", nil, "", 2, 3},
+		{"used", http.MethodPost, "/useSoon?d=10", url.Values{"id": {"2"}, "n": {"0"}, "a": {"used"}}, "/useSoon?d=10", 1, 2},
+		{"waste", http.MethodPost, "/useSoon", url.Values{"id": {"2"}, "n": {"1"}, "a": {"waste"}}, "/useSoon?d=3", 1, 1},
+		{"date", http.MethodPost, "/useSoon", url.Values{"id": {"2"}, "n": {"0"}, "a": {"date"}, "bb": {nextWeek}}, "/useSoon?d=3", 2, 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ld := pantryList()
+			w := call(UseSoonHandler, ld, tt.method, tt.target, tt.form)
+			if tt.location == "" {
+				assert.EqualValues(t, http.StatusOK, w.Code)
+				assert.Contains(t, w.Body.String(), "Milch")
+			} else {
+				assert.EqualValues(t, http.StatusFound, w.Code)
+				assert.EqualValues(t, tt.location, w.Header().Get("Location"))
+			}
+			milk := ld.ItemById(2)
+			assert.EqualValues(t, tt.batches, len(milk.Batches))
+			assert.EqualValues(t, tt.stock, milk.Stock)
+		})
+	}
+}
+
+func TestUseSoonHandler_Date(t *testing.T) {
+	ld := pantryList()
+	nextWeek := time.Now().AddDate(0, 0, 7)
+	call(UseSoonHandler, ld, http.MethodPost, "/useSoon", url.Values{"id": {"2"}, "n": {"0"}, "a": {"date"}, "bb": {nextWeek.Format("2006-01-02")}})
+	milk := ld.ItemById(2)
+	assert.EqualValues(t, 2, milk.Batches[0].Quantity, "batches are sorted by date")
+	assert.EqualValues(t, nextWeek.Format("2006-01-02"), milk.Batches[1].BestBefore.Format("2006-01-02"))
+}
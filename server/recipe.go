@@ -0,0 +1,141 @@
+package server
+
+import (
+	"github.com/hneemann/shopping/item"
+	"net/http"
+	"strconv"
+)
+
+var recipesTemp = Templates.Lookup("recipes.html")
+
+func RecipesHandler(w http.ResponseWriter, r *http.Request) {
+	if data, ok := r.Context().Value("data").(*item.ListData); ok {
+		p := formParams(r)
+		if r.Method == http.MethodPost {
+			if p.Has("del") {
+				id := p.Int("del")
+				if p.Err() == nil {
+					data.DeleteRecipe(id)
+					http.Redirect(w, r, "/recipes", http.StatusFound)
+					return
+				}
+			} else {
+				name := p.Str("name")
+				servings := p.OptInt("servings", 0)
+				if len(name) > 0 && p.Err() == nil {
+					recipe := data.AddRecipe(name, servings)
+					http.Redirect(w, r, "/recipe/?id="+strconv.Itoa(recipe.Id), http.StatusFound)
+					return
+				}
+			}
+		}
+		badRequestView(w, p.Err())
+
+		var d = struct {
+			*item.ListData
+			Error error
+		}{
+			ListData: data,
+			Error:    p.Err(),
+		}
+		err := recipesTemp.Execute(w, d)
+		if err != nil {
+			data.Logger().Error("could not render page", "template", "recipes.html", "err", err)
+		}
+	}
+}
+
+var recipeTemp = Templates.Lookup("recipe.html")
+
+func RecipeHandler(w http.ResponseWriter, r *http.Request) {
+	if data, ok := r.Context().Value("data").(*item.ListData); ok {
+		p := formParams(r)
+		recipe := data.RecipeById(p.Int("id"))
+		if recipe == nil {
+			http.Redirect(w, r, "/recipes", http.StatusFound)
+			return
+		}
+		if r.Method == http.MethodPost {
+			switch p.OneOf("a", "save", "ing") {
+			case "save":
+				name := p.Str("name")
+				servings := p.OptInt("servings", recipe.Servings)
+				if p.Err() == nil {
+					data.EditRecipe(recipe, name, servings)
+				}
+			case "ing":
+				itemId := p.Id("item", data)
+				quantity := p.OptFloat("quantity", 0)
+				if p.Err() == nil {
+					data.SetIngredient(recipe, itemId, quantity)
+				}
+			}
+			if p.Err() == nil {
+				http.Redirect(w, r, "/recipe/?id="+strconv.Itoa(recipe.Id), http.StatusFound)
+				return
+			}
+		}
+		badRequestView(w, p.Err())
+
+		var d = struct {
+			Recipe *item.Recipe
+			Data   *item.ListData
+			Error  error
+		}{
+			Recipe: recipe,
+			Data:   data,
+			Error:  p.Err(),
+		}
+		err := recipeTemp.Execute(w, d)
+		if err != nil {
+			data.Logger().Error("could not render page", "template", "recipe.html", "err", err)
+		}
+	}
+}
+
+var mealPlanTemp = Templates.Lookup("mealPlan.html")
+
+func MealPlanHandler(w http.ResponseWriter, r *http.Request) {
+	if data, ok := r.Context().Value("data").(*item.ListData); ok {
+		p := formParams(r)
+		if r.Method == http.MethodPost {
+			switch p.OneOf("a", "plan", "rm", "add", "week") {
+			case "plan":
+				day := p.Int("day")
+				recipe := p.Int("recipe")
+				servings := p.OptInt("servings", 0)
+				if p.Err() == nil {
+					data.PlanMeal(day, recipe, servings)
+				}
+			case "rm":
+				id := p.MealId("id", data)
+				if p.Err() == nil {
+					data.RemoveMeal(id)
+				}
+			case "week":
+				data.NewWeek()
+			case "add":
+				data.AddMealPlan()
+				http.Redirect(w, r, "/", http.StatusFound)
+				return
+			}
+			if p.Err() == nil {
+				http.Redirect(w, r, "/mealPlan", http.StatusFound)
+				return
+			}
+		}
+		badRequestView(w, p.Err())
+
+		var d = struct {
+			*item.ListData
+			Error error
+		}{
+			ListData: data,
+			Error:    p.Err(),
+		}
+		err := mealPlanTemp.Execute(w, d)
+		if err != nil {
+			data.Logger().Error("could not render page", "template", "mealPlan.html", "err", err)
+		}
+	}
+}
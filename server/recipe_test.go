@@ -0,0 +1,88 @@
+package server
+
+import (
+	"github.com/hneemann/shopping/item"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/url"
+	"testing"
+)
+
+func recipeList() *item.ListData {
+	ld := testList()
+	r := ld.AddRecipe("Pfannkuchen", 2)
+	r.SetIngredient(2, 0.5)
+	return ld
+}
+
+func TestRecipesHandler(t *testing.T) {
+	ld := recipeList()
+	w := call(RecipesHandler, ld, http.MethodGet, "/recipes", nil)
+	assert.EqualValues(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), "Pfannkuchen")
+
+	w = call(RecipesHandler, ld, http.MethodPost, "/recipes", url.Values{"name": {"Apfelmus"}, "servings": {"4"}})
+	assert.EqualValues(t, http.StatusFound, w.Code)
+	assert.EqualValues(t, "/recipe/?id=2", w.Header().Get("Location"))
+	assert.EqualValues(t, 4, ld.RecipeById(2).Servings)
+
+	w = call(RecipesHandler, ld, http.MethodPost, "/recipes", url.Values{"del": {"1"}})
+	assert.EqualValues(t, http.StatusFound, w.Code)
+	assert.EqualValues(t, "/recipes", w.Header().Get("Location"))
+	assert.Nil(t, ld.RecipeById(1))
+}
+
+func TestRecipeHandler(t *testing.T) {
+	ld := recipeList()
+	w := call(RecipeHandler, ld, http.MethodGet, "/recipe/?id=1", nil)
+	assert.EqualValues(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), "Pfannkuchen")
+
+	w = call(RecipeHandler, ld, http.MethodGet, "/recipe/?id=7", nil)
+	assert.EqualValues(t, http.StatusFound, w.Code)
+	assert.EqualValues(t, "/recipes", w.Header().Get("Location"))
+
+	w = call(RecipeHandler, ld, http.MethodPost, "/recipe/", url.Values{"id": {"1"}, "a": {"save"}, "name": {"Crêpes"}, "servings": {"3"}})
+	assert.EqualValues(t, http.StatusFound, w.Code)
+	assert.EqualValues(t, "/recipe/?id=1", w.Header().Get("Location"))
+	r := ld.RecipeById(1)
+	assert.EqualValues(t, "Crêpes", r.Name)
+	assert.EqualValues(t, 3, r.Servings)
+
+	w = call(RecipeHandler, ld, http.MethodPost, "/recipe/", url.Values{"id": {"1"}, "a": {"ing"}, "item": {"1"}, "quantity": {"0,25"}})
+	assert.EqualValues(t, http.StatusFound, w.Code)
+	assert.EqualValues(t, []item.Ingredient{{ItemId: 2, Quantity: 0.5}, {ItemId: 1, Quantity: 0.25}}, r.Ingredients)
+
+	w = call(RecipeHandler, ld, http.MethodPost, "/recipe/", url.Values{"id": {"1"}, "a": {"ing"}, "item": {"42"}})
+	assert.EqualValues(t, http.StatusBadRequest, w.Code)
+	assert.EqualValues(t, 2, len(r.Ingredients))
+}
+
+func TestMealPlanHandler(t *testing.T) {
+	ld := recipeList()
+	w := call(MealPlanHandler, ld, http.MethodPost, "/mealPlan", url.Values{"a": {"plan"}, "day": {"2"}, "recipe": {"1"}, "servings": {"4"}})
+	assert.EqualValues(t, http.StatusFound, w.Code)
+	assert.EqualValues(t, "/mealPlan", w.Header().Get("Location"))
+	assert.EqualValues(t, []item.PlannedMeal{{Id: 1, Day: 2, RecipeId: 1, Servings: 4}}, ld.MealPlan)
+
+	w = call(MealPlanHandler, ld, http.MethodGet, "/mealPlan", nil)
+	assert.EqualValues(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), "Pfannkuchen")
+
+	w = call(MealPlanHandler, ld, http.MethodPost, "/mealPlan", url.Values{"a": {"add"}})
+	assert.EqualValues(t, http.StatusFound, w.Code)
+	assert.EqualValues(t, "/", w.Header().Get("Location"))
+	assert.EqualValues(t, 1, ld.ItemById(2).QuantityRequired)
+
+	w = call(MealPlanHandler, ld, http.MethodGet, "/mealPlan", nil)
+	assert.Contains(t, w.Body.String(), "Neue Woche beginnen")
+	w = call(MealPlanHandler, ld, http.MethodPost, "/mealPlan", url.Values{"a": {"week"}})
+	assert.EqualValues(t, http.StatusFound, w.Code)
+	assert.True(t, ld.MealPlanPending())
+	w = call(MealPlanHandler, ld, http.MethodPost, "/mealPlan", url.Values{"a": {"add"}})
+	assert.EqualValues(t, 2, ld.ItemById(2).QuantityRequired)
+
+	w = call(MealPlanHandler, ld, http.MethodPost, "/mealPlan", url.Values{"a": {"rm"}, "id": {"1"}})
+	assert.EqualValues(t, http.StatusFound, w.Code)
+	assert.Empty(t, ld.MealPlan)
+}
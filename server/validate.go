@@ -0,0 +1,184 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/hneemann/shopping/item"
+	"log/slog"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// inputError describes an invalid request parameter
+type inputError struct {
+	Param string `json:"param"`
+	Value string `json:"value"`
+	Msg   string `json:"error"`
+}
+
+func (e *inputError) Error() string {
+	return fmt.Sprintf("Ungültiger Wert '%s' für '%s': %s", e.Value, e.Param, e.Msg)
+}
+
+// params reads and validates request parameters.
+// Only the first error is kept, so the values can be read one after
+// the other and the error is checked at the end.
+type params struct {
+	get func(string) string
+	err error
+}
+
+func formParams(r *http.Request) *params {
+	return &params{get: r.FormValue}
+}
+
+func (p *params) fail(name, value, msg string) {
+	if p.err == nil {
+		p.err = &inputError{Param: name, Value: value, Msg: msg}
+	}
+}
+
+// Err returns the first error found
+func (p *params) Err() error {
+	return p.err
+}
+
+// Has returns true if the parameter is present and not empty
+func (p *params) Has(name string) bool {
+	return strings.TrimSpace(p.get(name)) != ""
+}
+
+// Str returns the trimmed parameter
+func (p *params) Str(name string) string {
+	return strings.TrimSpace(p.get(name))
+}
+
+// Int returns the integer parameter, the parameter is required
+func (p *params) Int(name string) int {
+	str := p.Str(name)
+	if str == "" {
+		p.fail(name, str, "Wert fehlt")
+		return 0
+	}
+	i, err := strconv.Atoi(str)
+	if err != nil {
+		p.fail(name, str, "keine ganze Zahl")
+		return 0
+	}
+	return i
+}
+
+// OptInt returns the non-negative integer parameter or def if the parameter is missing
+func (p *params) OptInt(name string, def int) int {
+	if !p.Has(name) {
+		return def
+	}
+	i := p.Int(name)
+	if i < 0 {
+		p.fail(name, p.Str(name), "negativer Wert")
+		return def
+	}
+	return i
+}
+
+// Float returns the float parameter, the parameter is required
+func (p *params) Float(name string) float64 {
+	str := p.Str(name)
+	if str == "" {
+		p.fail(name, str, "Wert fehlt")
+		return 0
+	}
+	f, err := strconv.ParseFloat(strings.ReplaceAll(str, ",", "."), 64)
+	if err != nil || math.IsNaN(f) || math.IsInf(f, 0) {
+		p.fail(name, str, "keine Zahl")
+		return 0
+	}
+	return f
+}
+
+// OptFloat returns the non-negative float parameter or def if the parameter is missing
+func (p *params) OptFloat(name string, def float64) float64 {
+	if !p.Has(name) {
+		return def
+	}
+	f := p.Float(name)
+	if f < 0 {
+		p.fail(name, p.Str(name), "negativer Wert")
+		return def
+	}
+	return f
+}
+
+// Id returns the id of an item of the given list, the parameter is required
+func (p *params) Id(name string, data *item.ListData) int {
+	id := p.Int(name)
+	if p.err == nil && !data.IdValid(id) {
+		p.fail(name, p.Str(name), "unbekannter Artikel")
+	}
+	return id
+}
+
+// TempId returns the id of a temporary entry which needs to exist
+func (p *params) TempId(name string, data *item.ListData) int {
+	id := p.Int(name)
+	if p.err == nil && data.TempById(id) == nil {
+		p.fail(name, p.Str(name), "unbekannter Eintrag")
+	}
+	return id
+}
+
+// MealId returns the id of a planned meal which needs to exist
+func (p *params) MealId(name string, data *item.ListData) int {
+	id := p.Int(name)
+	if p.err == nil && data.MealById(id) == nil {
+		p.fail(name, p.Str(name), "unbekannte Mahlzeit")
+	}
+	return id
+}
+
+// List returns the name of a list of the account which needs to exist
+func (p *params) List(name string, account *item.Account) string {
+	list := p.Str(name)
+	if p.err == nil && account.ListByName(list) == nil {
+		p.fail(name, list, "unbekannte Liste")
+	}
+	return list
+}
+
+// OneOf returns the parameter which needs to be one of the given values
+func (p *params) OneOf(name string, values ...string) string {
+	str := p.Str(name)
+	for _, v := range values {
+		if str == v {
+			return str
+		}
+	}
+	p.fail(name, str, "nicht erlaubt")
+	return ""
+}
+
+// writeBadRequest writes a 400 response with a json body describing the error
+func writeBadRequest(w http.ResponseWriter, err error) {
+	slog.Warn("bad request", "err", err)
+	var ie *inputError
+	if !errors.As(err, &ie) {
+		ie = &inputError{Msg: err.Error()}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusBadRequest)
+	err = json.NewEncoder(w).Encode(ie)
+	if err != nil {
+		slog.Error("could not write response", "err", err)
+	}
+}
+
+// badRequestView sets the status code used to render a view showing an input error
+func badRequestView(w http.ResponseWriter, err error) {
+	if err != nil {
+		slog.Warn("bad request", "err", err)
+		w.WriteHeader(http.StatusBadRequest)
+	}
+}